@@ -0,0 +1,158 @@
+package goinvoke
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"github.com/jamesits/goinvoke/utils"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// hostArchitecture describes the binaries the current process can load, or returns "" if it is not known.
+func hostArchitecture() string {
+	switch runtime.GOOS {
+	case "windows":
+		if machine, ok := utils.HostPEMachine(); ok {
+			return "PE " + utils.PEMachineName(machine)
+		}
+	case "darwin", "ios":
+		if cpu, ok := utils.HostMachOCPU(); ok {
+			return "Mach-O " + cpu.String()
+		}
+	default:
+		if class, data, machine, ok := utils.HostELFMachine(); ok {
+			return fmt.Sprintf("ELF %v %v %v", class, data, machine)
+		}
+	}
+	return ""
+}
+
+// imageArchitectures describes the architectures a binary is built for, in the same terms as hostArchitecture. Fat
+// Mach-O files have several. Files which are not shared libraries are errors wrapping ErrorNotSharedLibrary.
+func imageArchitectures(r io.ReaderAt) ([]string, error) {
+	head := make([]byte, 512)
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		f, err := elf.NewFile(r)
+		if err != nil {
+			return nil, err
+		}
+		if f.Type != elf.ET_DYN {
+			return nil, fmt.Errorf("it is an ELF file of type %v, rather than a shared object", f.Type)
+		}
+		return []string{fmt.Sprintf("ELF %v %v %v", f.Class, f.Data, f.Machine)}, nil
+
+	case bytes.HasPrefix(head, []byte("MZ")):
+		f, err := pe.NewFile(r)
+		if err != nil {
+			return nil, err
+		}
+		return []string{"PE " + utils.PEMachineName(f.Machine)}, nil
+
+	case bytes.HasPrefix(head, []byte{0xca, 0xfe, 0xba, 0xbe}):
+		f, err := macho.NewFatFile(r)
+		if err != nil {
+			return nil, err
+		}
+		var ret []string
+		for _, arch := range f.Arches {
+			ret = append(ret, "Mach-O "+arch.Cpu.String())
+		}
+		return ret, nil
+
+	case bytes.HasPrefix(head, []byte{0xcf, 0xfa, 0xed, 0xfe}), bytes.HasPrefix(head, []byte{0xce, 0xfa, 0xed, 0xfe}):
+		f, err := macho.NewFile(r)
+		if err != nil {
+			return nil, err
+		}
+		return []string{"Mach-O " + f.Cpu.String()}, nil
+
+	case bytes.HasPrefix(head, []byte("!<arch>\n")):
+		return nil, errors.New("it is a static archive or an import library")
+
+	case isText(head):
+		if bytes.Contains(head, []byte("GROUP")) || bytes.Contains(head, []byte("INPUT")) {
+			return nil, errors.New("it is a text file, probably a linker script")
+		}
+		return nil, errors.New("it is a text file")
+
+	default:
+		return nil, errors.New("unknown binary format")
+	}
+}
+
+// isText tests if b looks like the beginning of a text file.
+func isText(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	// the last rune might be cut
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 && len(b) >= utf8.UTFMax {
+			return false
+		}
+		if r < 0x20 && !strings.ContainsRune("\t\n\r\f", r) {
+			return false
+		}
+		b = b[size:]
+	}
+	return true
+}
+
+// checkArchitecture tests if the current process can load the binary named name, whose content is r.
+func checkArchitecture(name string, r io.ReaderAt) error {
+	actual, err := imageArchitectures(r)
+	if err != nil {
+		return fmt.Errorf("%w: \"%s\": %v", ErrorNotSharedLibrary, name, err)
+	}
+
+	expected := hostArchitecture()
+	if expected == "" {
+		return nil
+	}
+	for _, a := range actual {
+		if a == expected {
+			return nil
+		}
+	}
+	return &ArchitectureMismatchError{
+		Path:     name,
+		Actual:   strings.Join(actual, ", "),
+		Expected: expected,
+	}
+}
+
+// checkArchitectureFile is like checkArchitecture, for the DLL file at path, or the shared object it refers to if it is a
+// linker script. Bare names are searched for by the loader, and files which cannot be opened are reported by it, so
+// they are not checked.
+func checkArchitectureFile(path string) error {
+	if utils.IsImplicitRelativePath(path) {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	err = checkArchitecture(path, f)
+	if errors.Is(err, ErrorNotSharedLibrary) && runtime.GOOS != "windows" {
+		// the loader follows linker scripts to the shared object they refer to
+		if target, ok := utils.LinkerScriptTarget(path); ok {
+			return checkArchitectureFile(target)
+		}
+	}
+	return err
+}
@@ -0,0 +1,270 @@
+package goinvoke
+
+import (
+	"fmt"
+	"github.com/jamesits/goinvoke/utils"
+	"math"
+	"reflect"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Typed call helpers.
+//
+// Supported argument types are integers (including uintptr and bool), float32, float64, pointers (including
+// unsafe.Pointer) and strings. Strings are passed as zero-terminated "const char *", and are kept alive until the call
+// returns.
+//
+// Supported return types are all of the above, plus struct{} for functions returning void. A returned string is
+// copied from a zero-terminated "const char *"; a NULL pointer becomes an empty string.
+//
+// Floating-point arguments and return values are only supported where purego passes them in floating-point registers:
+// on darwin and freebsd, and on linux without cgo (CGO_ENABLED=0), all on amd64 or arm64. Elsewhere they are rejected,
+// as the called function would silently get garbage. purego puts each argument in both the integer and the
+// floating-point register of its position, so floating-point arguments cannot be mixed with other arguments (e.g.
+// ldexp(double, int)), and at most 8 of them can be passed; such calls are rejected too.
+//
+// At most 9 arguments can be passed on unix, and 42 on Windows; the underlying syscalls panic beyond that.
+
+// Call1 calls p with args converted to raw arguments, and converts the primary return value to R.
+//
+// If any of the types is not supported, p is not called, and the returned error wraps ErrorUnsupportedType. If there
+// are too many arguments, p is not called either, and the returned error wraps ErrorTooManyArguments.
+// Otherwise, the returned error is the last error reported by p.Call() if it is not zero. As with Proc.Call, callers
+// must inspect the return value to decide whether an error occurred before consulting the error.
+func Call1[R any](p FunctionPointer, args ...any) (R, error) {
+	var ret R
+
+	if len(args) > maxCallArgs {
+		return ret, fmt.Errorf("%w: %d, at most %d are supported", ErrorTooManyArguments, len(args), maxCallArgs)
+	}
+
+	err := checkReturnType(typeOf[R]())
+	if err != nil {
+		return ret, err
+	}
+
+	raw := make([]uintptr, len(args))
+	types := make([]reflect.Type, len(args))
+	var keepAlive []any
+	for i, a := range args {
+		raw[i], err = toUintptr(a, &keepAlive)
+		if err != nil {
+			return ret, fmt.Errorf("argument %d: %w", i, err)
+		}
+		types[i] = reflect.TypeOf(a)
+	}
+	err = checkFloatArguments(types)
+	if err != nil {
+		return ret, err
+	}
+
+	r1, r2, err := p.Call(raw...)
+	runtime.KeepAlive(args)
+	runtime.KeepAlive(keepAlive)
+	if errno, ok := err.(syscall.Errno); ok && errno == 0 {
+		err = nil
+	}
+
+	return fromUintptr[R](r1, r2), err
+}
+
+// Func0 wraps p into a typed Go function. It panics if any of the types is not supported.
+func Func0[R any](p FunctionPointer) func() R {
+	mustCheckTypes[R]()
+	return func() R {
+		ret, _ := Call1[R](p)
+		return ret
+	}
+}
+
+// Func1 wraps p into a typed Go function. It panics if any of the types is not supported.
+func Func1[A, R any](p FunctionPointer) func(A) R {
+	mustCheckTypes[R](typeOf[A]())
+	return func(a A) R {
+		ret, _ := Call1[R](p, a)
+		return ret
+	}
+}
+
+// Func2 wraps p into a typed Go function. It panics if any of the types is not supported.
+func Func2[A, B, R any](p FunctionPointer) func(A, B) R {
+	mustCheckTypes[R](typeOf[A](), typeOf[B]())
+	return func(a A, b B) R {
+		ret, _ := Call1[R](p, a, b)
+		return ret
+	}
+}
+
+// Func3 wraps p into a typed Go function. It panics if any of the types is not supported.
+func Func3[A, B, C, R any](p FunctionPointer) func(A, B, C) R {
+	mustCheckTypes[R](typeOf[A](), typeOf[B](), typeOf[C]())
+	return func(a A, b B, c C) R {
+		ret, _ := Call1[R](p, a, b, c)
+		return ret
+	}
+}
+
+// Func4 wraps p into a typed Go function. It panics if any of the types is not supported.
+func Func4[A, B, C, D, R any](p FunctionPointer) func(A, B, C, D) R {
+	mustCheckTypes[R](typeOf[A](), typeOf[B](), typeOf[C](), typeOf[D]())
+	return func(a A, b B, c C, d D) R {
+		ret, _ := Call1[R](p, a, b, c, d)
+		return ret
+	}
+}
+
+// Func5 wraps p into a typed Go function. It panics if any of the types is not supported.
+func Func5[A, B, C, D, E, R any](p FunctionPointer) func(A, B, C, D, E) R {
+	mustCheckTypes[R](typeOf[A](), typeOf[B](), typeOf[C](), typeOf[D](), typeOf[E]())
+	return func(a A, b B, c C, d D, e E) R {
+		ret, _ := Call1[R](p, a, b, c, d, e)
+		return ret
+	}
+}
+
+// Func6 wraps p into a typed Go function. It panics if any of the types is not supported.
+func Func6[A, B, C, D, E, F, R any](p FunctionPointer) func(A, B, C, D, E, F) R {
+	mustCheckTypes[R](typeOf[A](), typeOf[B](), typeOf[C](), typeOf[D](), typeOf[E](), typeOf[F]())
+	return func(a A, b B, c C, d D, e E, f F) R {
+		ret, _ := Call1[R](p, a, b, c, d, e, f)
+		return ret
+	}
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func mustCheckTypes[R any](args ...reflect.Type) {
+	err := checkReturnType(typeOf[R]())
+	if err != nil {
+		panic(err)
+	}
+
+	for i, t := range args {
+		err = checkArgumentType(t)
+		if err != nil {
+			panic(fmt.Errorf("argument %d: %w", i, err))
+		}
+	}
+	err = checkFloatArguments(args)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func checkArgumentType(t reflect.Type) error {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Pointer, reflect.UnsafePointer,
+		reflect.String:
+		return nil
+	case reflect.Float32, reflect.Float64:
+		if floatCallsSupported {
+			return nil
+		}
+		return fmt.Errorf("%w %s on this platform (with cgo on linux, try CGO_ENABLED=0)", ErrorUnsupportedType, t)
+	}
+
+	return fmt.Errorf("%w %s", ErrorUnsupportedType, t)
+}
+
+// maxFloatCallArgs is the number of floating-point registers arguments are passed in, on both amd64 and arm64.
+const maxFloatCallArgs = 8
+
+// checkFloatArguments tests if arguments of the given types, each of which is supported, can be passed together. A nil
+// type is the one of a nil argument.
+func checkFloatArguments(types []reflect.Type) error {
+	floats := 0
+	for _, t := range types {
+		if t != nil && (t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64) {
+			floats++
+		}
+	}
+
+	switch {
+	case floats == 0:
+		return nil
+	case floats != len(types):
+		return fmt.Errorf("%w: floating-point arguments cannot be mixed with other arguments", ErrorUnsupportedType)
+	case floats > maxFloatCallArgs:
+		return fmt.Errorf("%w: %d floating-point arguments, at most %d are supported", ErrorTooManyArguments, floats, maxFloatCallArgs)
+	}
+	return nil
+}
+
+func checkReturnType(t reflect.Type) error {
+	if t.Kind() == reflect.Struct && t.NumField() == 0 {
+		return nil
+	}
+
+	return checkArgumentType(t)
+}
+
+// toUintptr converts a Go value to a raw argument. Anything allocated during the conversion is appended to keepAlive.
+func toUintptr(v any, keepAlive *[]any) (uintptr, error) {
+	if v == nil {
+		return 0, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	err := checkArgumentType(rv.Type())
+	if err != nil {
+		return 0, err
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uintptr(rv.Int()), nil
+	case reflect.Float32:
+		return uintptr(math.Float32bits(float32(rv.Float()))), nil
+	case reflect.Float64:
+		return uintptr(math.Float64bits(rv.Float())), nil
+	case reflect.Pointer, reflect.UnsafePointer:
+		return rv.Pointer(), nil
+	case reflect.String:
+		buf := append([]byte(rv.String()), 0)
+		*keepAlive = append(*keepAlive, buf)
+		return uintptr(unsafe.Pointer(&buf[0])), nil
+	default:
+		return uintptr(rv.Uint()), nil
+	}
+}
+
+// fromUintptr converts raw return values to R, which must have been checked by checkReturnType.
+func fromUintptr[R any](r1, r2 uintptr) R {
+	var ret R
+	rv := reflect.ValueOf(&ret).Elem()
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		rv.SetBool(r1 != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		rv.SetInt(int64(r1))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		rv.SetUint(uint64(r1))
+	case reflect.Float32:
+		rv.SetFloat(float64(math.Float32frombits(uint32(r2))))
+	case reflect.Float64:
+		rv.SetFloat(math.Float64frombits(uint64(r2)))
+	case reflect.Pointer:
+		rv.Set(reflect.NewAt(rv.Type().Elem(), *(*unsafe.Pointer)(unsafe.Pointer(&r1))))
+	case reflect.UnsafePointer:
+		rv.SetPointer(*(*unsafe.Pointer)(unsafe.Pointer(&r1)))
+	case reflect.String:
+		if r1 != 0 {
+			rv.SetString(utils.UintPtrToString(r1))
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,42 @@
+//go:build linux && !cgo && (amd64 || arm64)
+
+package goinvoke
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+// floating-point values are only supported by purego without cgo
+func TestFuncFloat(t *testing.T) {
+	m := LibM{}
+	err := Unmarshal("libm.so.6", &m)
+	assert.NoError(t, err)
+
+	sqrt := Func1[float64, float64](m.Sqrt)
+	assert.EqualValues(t, math.Sqrt2, sqrt(2))
+}
+
+type libMMixed struct {
+	Ldexp *Proc `func:"ldexp"`
+	Fmax  *Proc `func:"fmax"`
+}
+
+// purego passes arguments by position, so mixing floating-point and integer arguments would give garbage
+func TestFuncFloatMixed(t *testing.T) {
+	m := libMMixed{}
+	err := Unmarshal("libm.so.6", &m)
+	assert.NoError(t, err)
+
+	_, err = Call1[float64](m.Ldexp, 1.5, int32(3))
+	assert.ErrorIs(t, err, ErrorUnsupportedType)
+	assert.Panics(t, func() { Func2[float64, int32, float64](m.Ldexp) })
+
+	r, err := Call1[float64](m.Fmax, 1.5, 2.5)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2.5, r)
+
+	_, err = Call1[float64](m.Fmax, 1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0)
+	assert.ErrorIs(t, err, ErrorTooManyArguments)
+}
@@ -0,0 +1,91 @@
+//go:build linux
+
+package goinvoke
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+type libCTyped struct {
+	StrLen *Proc `func:"strlen"`
+	StrCmp *Proc `func:"strcmp"`
+	StrChr *Proc `func:"strchr"`
+	Abs    *Proc `func:"abs"`
+}
+
+func TestCall1(t *testing.T) {
+	l := libCTyped{}
+	err := Unmarshal("libc.so.6", &l)
+	assert.NoError(t, err)
+
+	// errno is not meaningful for these functions, so it is not checked
+	n, _ := Call1[int](l.StrLen, "114514")
+	assert.EqualValues(t, 6, n)
+
+	// sign extension of both arguments and return values
+	a, _ := Call1[int32](l.Abs, int32(-42))
+	assert.EqualValues(t, 42, a)
+
+	// strings are returned as copies
+	s, _ := Call1[string](l.StrChr, "hello, world", 'w')
+	assert.EqualValues(t, "world", s)
+	s, _ = Call1[string](l.StrChr, "hello, world", 'x')
+	assert.EqualValues(t, "", s)
+
+	// pointers
+	buf := []byte("abc\x00")
+	p, _ := Call1[*byte](l.StrChr, &buf[0], 'b')
+	assert.EqualValues(t, unsafe.Pointer(&buf[1]), unsafe.Pointer(p))
+
+	// unsupported types
+	_, err = Call1[int](l.StrLen, []byte("abc"))
+	assert.ErrorIs(t, err, ErrorUnsupportedType)
+	_, err = Call1[[]byte](l.StrLen, "abc")
+	assert.ErrorIs(t, err, ErrorUnsupportedType)
+	if !floatCallsSupported {
+		_, err = Call1[int](l.Abs, 1.5)
+		assert.ErrorIs(t, err, ErrorUnsupportedType)
+		_, err = Call1[float32](l.Abs, 1)
+		assert.ErrorIs(t, err, ErrorUnsupportedType)
+	}
+
+	// too many arguments
+	_, err = Call1[int](l.Abs, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	assert.ErrorIs(t, err, ErrorTooManyArguments)
+}
+
+func TestCheckFloatArguments(t *testing.T) {
+	f64, i32 := typeOf[float64](), typeOf[int32]()
+	assert.NoError(t, checkFloatArguments(nil))
+	assert.NoError(t, checkFloatArguments([]reflect.Type{i32, i32}))
+	assert.NoError(t, checkFloatArguments([]reflect.Type{f64, typeOf[float32]()}))
+
+	// e.g. ldexp(double, int)
+	assert.ErrorIs(t, checkFloatArguments([]reflect.Type{f64, i32}), ErrorUnsupportedType)
+	assert.ErrorIs(t, checkFloatArguments([]reflect.Type{f64, nil}), ErrorUnsupportedType)
+	assert.ErrorIs(t, checkFloatArguments([]reflect.Type{f64, f64, f64, f64, f64, f64, f64, f64, f64}), ErrorTooManyArguments)
+}
+
+func TestFunc(t *testing.T) {
+	l := libCTyped{}
+	err := Unmarshal("libc.so.6", &l)
+	assert.NoError(t, err)
+
+	strcmp := Func2[string, string, int32](l.StrCmp)
+	assert.EqualValues(t, 0, strcmp("A", "A"))
+	assert.Less(t, strcmp("A", "B"), int32(0))
+	assert.Greater(t, strcmp("B", "A"), int32(0))
+
+	// void return values
+	strlen := Func1[string, struct{}](l.StrLen)
+	assert.EqualValues(t, struct{}{}, strlen("abc"))
+
+	assert.Panics(t, func() { Func1[[]byte, int](l.StrLen) })
+	assert.Panics(t, func() { Func1[string, map[int]int](l.StrLen) })
+	if !floatCallsSupported {
+		assert.Panics(t, func() { Func1[float64, float64](l.Abs) })
+	}
+}
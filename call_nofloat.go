@@ -0,0 +1,7 @@
+//go:build !((darwin || freebsd || (linux && !cgo)) && (amd64 || arm64))
+
+package goinvoke
+
+// floatCallsSupported tells if FunctionPointer.Call passes floating-point values in floating-point registers, which
+// purego only does on its SysV path.
+const floatCallsSupported = false
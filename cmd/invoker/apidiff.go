@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// kinds of API changes
+const (
+	changeAdded     = "added"
+	changeRemoved   = "removed"
+	changeRenamed   = "renamed"
+	changeOrdinal   = "ordinal"
+	changeForwarded = "forwarded"
+	changeVersion   = "version"
+)
+
+// apiChange is a difference between the exports of two versions of a library.
+type apiChange struct {
+	Kind     string `json:"kind"`
+	Symbol   string `json:"symbol"` // the old name if renamed; "@<ordinal>" for ordinal-only exports
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+	Breaking bool   `json:"breaking"`
+}
+
+func (c apiChange) String() string {
+	marker := " "
+	if c.Breaking {
+		marker = "!"
+	}
+
+	switch {
+	case c.Old != "" && c.New != "":
+		return fmt.Sprintf("%s %-9s %s: %s -> %s", marker, c.Kind, c.Symbol, c.Old, c.New)
+	case c.New != "":
+		return fmt.Sprintf("%s %-9s %s -> %s", marker, c.Kind, c.Symbol, c.New)
+	default:
+		return fmt.Sprintf("%s %-9s %s", marker, c.Kind, c.Symbol)
+	}
+}
+
+// diffLibraries compares the exports of two versions of a library. Removing or renaming an export, changing its
+// ordinal or its symbol version are breaking changes.
+//
+// Ordinals only matter if they are pinned (e.g. by a .def file) rather than assigned alphabetically by the linker:
+// only then is a removed export considered renamed if an added export took its ordinal, and a changed ordinal
+// breaking.
+func diffLibraries(oldLib, newLib *library) (changes []apiChange) {
+	oldByName, oldByOrdinal := indexSymbols(oldLib)
+	newByName, newByOrdinal := indexSymbols(newLib)
+
+	common, kept := 0, 0
+	for name, o := range oldByName {
+		if n, ok := newByName[name]; ok {
+			common++
+			if o.Ordinal == n.Ordinal {
+				kept++
+			}
+		}
+	}
+	pinned := common > 0 && kept*10 >= common*9
+
+	// exports in both versions
+	renamed := map[string]bool{}
+	for name, o := range oldByName {
+		n, ok := newByName[name]
+		if !ok {
+			// renamed, if another export took its ordinal
+			if pinned {
+				if n, ok := newByOrdinal[o.Ordinal]; ok && n.Name != name && oldByName[n.Name] == nil {
+					changes = append(changes, apiChange{Kind: changeRenamed, Symbol: filterName(*o), New: filterName(*n), Breaking: true})
+					renamed[filterName(*n)] = true
+					continue
+				}
+			}
+			changes = append(changes, apiChange{Kind: changeRemoved, Symbol: filterName(*o), Breaking: true})
+			continue
+		}
+
+		if o.Ordinal != n.Ordinal {
+			changes = append(changes, apiChange{Kind: changeOrdinal, Symbol: name, Old: strconv.FormatUint(uint64(o.Ordinal), 10), New: strconv.FormatUint(uint64(n.Ordinal), 10), Breaking: pinned})
+		}
+		if o.Forwarder != n.Forwarder && n.Forwarder != "" {
+			changes = append(changes, apiChange{Kind: changeForwarded, Symbol: name, Old: o.Forwarder, New: n.Forwarder})
+		}
+		if o.Version != n.Version {
+			changes = append(changes, apiChange{Kind: changeVersion, Symbol: name, Old: o.Version, New: n.Version, Breaking: true})
+		}
+	}
+
+	// ordinal-only exports
+	for ordinal, o := range oldByOrdinal {
+		if o.Name != "" {
+			continue
+		}
+		if n, ok := newByOrdinal[ordinal]; ok {
+			if n.Name != "" {
+				// an ordinal-only export got a name, which does not break anyone importing it by ordinal
+				changes = append(changes, apiChange{Kind: changeRenamed, Symbol: filterName(*o), New: n.Name})
+				renamed[n.Name] = true
+			}
+			continue
+		}
+		changes = append(changes, apiChange{Kind: changeRemoved, Symbol: filterName(*o), Breaking: true})
+	}
+
+	for name := range newByName {
+		if oldByName[name] == nil && !renamed[name] {
+			changes = append(changes, apiChange{Kind: changeAdded, Symbol: name})
+		}
+	}
+	for ordinal, n := range newByOrdinal {
+		if n.Name == "" && oldByOrdinal[ordinal] == nil {
+			changes = append(changes, apiChange{Kind: changeAdded, Symbol: filterName(*n)})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Symbol != changes[j].Symbol {
+			return changes[i].Symbol < changes[j].Symbol
+		}
+		return changes[i].Kind < changes[j].Kind
+	})
+	return
+}
+
+// indexSymbols indexes the exports of lib by name, and by ordinal (PE only).
+func indexSymbols(lib *library) (byName map[string]*symbol, byOrdinal map[uint32]*symbol) {
+	byName = map[string]*symbol{}
+	byOrdinal = map[uint32]*symbol{}
+	for i := range lib.Symbols {
+		s := &lib.Symbols[i]
+		if s.Name != "" {
+			byName[s.Name] = s
+		}
+		if lib.Format == formatPE {
+			byOrdinal[s.Ordinal] = s
+		}
+	}
+	return
+}
+
+// diffMain implements `invoker diff`, and returns the exit code.
+func diffMain(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print the changes in JSON")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s diff [-json] old.dll new.dll\n\nCompares the exports of two versions of a library, and exits with 1 if there are breaking changes.\n\n", selfExecutableName)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 64
+	}
+
+	var libs []*library
+	for _, p := range flags.Args() {
+		p, code, err := resolveLibraryPath(p)
+		if err != nil {
+			log.Print(err)
+			return code
+		}
+		lib, err := openLibrary(p)
+		if err != nil {
+			log.Printf("unable to read the DLL \"%s\": %v\n", p, err)
+			return 65
+		}
+		libs = append(libs, lib)
+	}
+	if libs[0].Format != libs[1].Format {
+		log.Printf("unable to compare a %s file with a %s file\n", libs[0].Format, libs[1].Format)
+		return 65
+	}
+
+	changes := diffLibraries(libs[0], libs[1])
+	err := printChanges(os.Stdout, changes, *jsonOutput)
+	if err != nil {
+		log.Printf("unable to print the changes: %v\n", err)
+		return 74
+	}
+
+	for _, c := range changes {
+		if c.Breaking {
+			return 1
+		}
+	}
+	return 0
+}
+
+func printChanges(w io.Writer, changes []apiChange, jsonOutput bool) error {
+	if jsonOutput {
+		if changes == nil {
+			changes = []apiChange{}
+		}
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(changes)
+	}
+
+	for _, c := range changes {
+		_, err := fmt.Fprintln(w, c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
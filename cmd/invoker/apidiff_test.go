@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiffLibrariesPE(t *testing.T) {
+	oldLib := &library{Format: formatPE, Symbols: []symbol{
+		{Name: "foo_init", Ordinal: 1},
+		{Name: "foo_old", Ordinal: 2},
+		{Name: "foo_gone", Ordinal: 3},
+		{Name: "foo_alloc", Ordinal: 4},
+		{Name: "foo_free", Ordinal: 5},
+		{Name: "foo_read", Ordinal: 6},
+		{Name: "foo_write", Ordinal: 7},
+		{Name: "foo_seek", Ordinal: 8},
+		{Name: "foo_close", Ordinal: 9},
+		{Name: "foo_flush", Ordinal: 10},
+		{Name: "foo_stat", Ordinal: 11},
+		{Name: "foo_moved", Ordinal: 12},
+		{Ordinal: 20},
+		{Ordinal: 21},
+	}}
+	newLib := &library{Format: formatPE, Symbols: []symbol{
+		{Name: "foo_init", Ordinal: 1},
+		{Name: "foo_new", Ordinal: 2},
+		{Name: "foo_alloc", Ordinal: 4, Forwarder: "bar.bar_alloc"},
+		{Name: "foo_free", Ordinal: 5},
+		{Name: "foo_read", Ordinal: 6},
+		{Name: "foo_write", Ordinal: 7},
+		{Name: "foo_seek", Ordinal: 8},
+		{Name: "foo_close", Ordinal: 9},
+		{Name: "foo_flush", Ordinal: 10},
+		{Name: "foo_stat", Ordinal: 11},
+		{Name: "foo_moved", Ordinal: 13},
+		{Name: "foo_added", Ordinal: 14},
+		{Name: "foo_named", Ordinal: 20},
+		{Ordinal: 22},
+	}}
+
+	assert.EqualValues(t, []apiChange{
+		{Kind: changeRenamed, Symbol: "@20", New: "foo_named"},
+		{Kind: changeRemoved, Symbol: "@21", Breaking: true},
+		{Kind: changeAdded, Symbol: "@22"},
+		{Kind: changeAdded, Symbol: "foo_added"},
+		{Kind: changeForwarded, Symbol: "foo_alloc", New: "bar.bar_alloc"},
+		{Kind: changeRemoved, Symbol: "foo_gone", Breaking: true},
+		{Kind: changeOrdinal, Symbol: "foo_moved", Old: "12", New: "13", Breaking: true},
+		{Kind: changeRenamed, Symbol: "foo_old", New: "foo_new", Breaking: true},
+	}, diffLibraries(oldLib, newLib))
+}
+
+func TestDiffLibrariesUnpinned(t *testing.T) {
+	// foo_b was inserted, so the linker moved every export after it
+	oldLib := &library{Format: formatPE, Symbols: []symbol{{Name: "foo_a", Ordinal: 1}, {Name: "foo_c", Ordinal: 2}, {Name: "foo_d", Ordinal: 3}}}
+	newLib := &library{Format: formatPE, Symbols: []symbol{{Name: "foo_a", Ordinal: 1}, {Name: "foo_b", Ordinal: 2}, {Name: "foo_c", Ordinal: 3}, {Name: "foo_d", Ordinal: 4}}}
+
+	changes := diffLibraries(oldLib, newLib)
+	assert.EqualValues(t, []apiChange{
+		{Kind: changeAdded, Symbol: "foo_b"},
+		{Kind: changeOrdinal, Symbol: "foo_c", Old: "2", New: "3"},
+		{Kind: changeOrdinal, Symbol: "foo_d", Old: "3", New: "4"},
+	}, changes)
+
+	var b bytes.Buffer
+	assert.NoError(t, printChanges(&b, changes, false))
+	assert.NotContains(t, b.String(), "!")
+}
+
+func TestDiffLibrariesELF(t *testing.T) {
+	oldLib := &library{Format: formatELF, Symbols: []symbol{{Name: "memcpy", Version: "GLIBC_2.2.5"}, {Name: "puts", Version: "GLIBC_2.2.5"}}}
+	newLib := &library{Format: formatELF, Symbols: []symbol{{Name: "memcpy", Version: "GLIBC_2.14"}, {Name: "puts", Version: "GLIBC_2.2.5"}}}
+
+	changes := diffLibraries(oldLib, newLib)
+	assert.EqualValues(t, []apiChange{
+		{Kind: changeVersion, Symbol: "memcpy", Old: "GLIBC_2.2.5", New: "GLIBC_2.14", Breaking: true},
+	}, changes)
+
+	var b bytes.Buffer
+	assert.NoError(t, printChanges(&b, changes, false))
+	assert.EqualValues(t, "! version   memcpy: GLIBC_2.2.5 -> GLIBC_2.14\n", b.String())
+
+	b.Reset()
+	assert.NoError(t, printChanges(&b, nil, true))
+	assert.EqualValues(t, "[]\n", b.String())
+}
@@ -1,27 +1,121 @@
 package main
 
-import "flag"
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// stringList is a flag which can be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
 
 var (
-	dllPath          string
-	outputType       string
-	outputFileName   string
-	trimPrefix       string
-	buildTags        string
-	selfGenerate     bool
-	preserveRealArg0 bool
-	lazy             bool
+	dllPaths           stringList
+	outputType         string
+	outputFileName     string
+	trimPrefixes       stringList
+	trimSuffixes       stringList
+	rewriteRules       stringList
+	renames            stringList
+	camelCase          bool
+	initialisms        bool
+	keepUnicode        bool
+	keepUnderscores    bool
+	buildTags          string
+	selfGenerate       bool
+	preserveRealArg0   bool
+	lazy               bool
+	style              string
+	headerFileName     string
+	includePatterns    stringList
+	excludePatterns    stringList
+	symbolsFileName    string
+	defFileName        string
+	templateFileName   string
+	check              bool
+	skipForwarders     bool
+	configFileName     string
+	dumpConfigFileName string
 )
 
+// subcommandUsages are printed by -help, besides the flags for generating code.
+var subcommandUsages = []string{
+	"-config goinvoke.yaml [-generate] [-check]",
+	"diff [-json] old.dll new.dll",
+	"exports [-format table|json|csv] [-include regexp] [-exclude regexp] file.dll",
+	"call [-ret type] file.dll function [type:value...]",
+	"deps [-path dir] file.dll",
+	"doctor [-type T [-pkg dir]] [-path dir] file.dll",
+}
+
 func init() {
-	flag.StringVar(&dllPath, "dll", "", "path to the DLL")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		_, _ = fmt.Fprintf(out, "Usage: %s -dll file.dll [flags]\n", selfExecutableName)
+		for _, usage := range subcommandUsages {
+			_, _ = fmt.Fprintf(out, "       %s %s\n", selfExecutableName, usage)
+		}
+		_, _ = fmt.Fprintf(out, "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Var(&dllPaths, "dll", "path to the DLL; repeat to generate one cross-platform struct of goinvoke.FunctionPointer from DLLs for different OSes")
 	flag.StringVar(&outputType, "type", "", "type name; default <Dllname>")
 	flag.StringVar(&outputFileName, "output", "", "output file name; default srcdir/<type>_dll.go")
-	flag.StringVar(&trimPrefix, "trim-prefix", "", "trim the `prefix` from the generated method names")
+	flag.Var(&trimPrefixes, "trim-prefix", "trim the `prefix` from the generated names; can be repeated, and the first matching one is trimmed")
+	flag.Var(&trimSuffixes, "trim-suffix", "trim the `suffix` from the generated names; can be repeated, and the first matching one is trimmed")
+	flag.Var(&rewriteRules, "rewrite", "replace the matches of a regular expression in the generated names, as a `pattern=replacement` rule; can be repeated")
+	flag.Var(&renames, "rename", "name an export, a type or a constant explicitly, as `cname=GoName`; can be repeated")
+	flag.BoolVar(&camelCase, "camel-case", false, "turn snake_case names into CamelCase")
+	flag.BoolVar(&initialisms, "initialisms", false, "spell initialisms like ID or URL in upper case")
+	flag.BoolVar(&keepUnicode, "keep-unicode", false, "keep non-ASCII letters in the generated names, instead of dropping them")
+	flag.BoolVar(&keepUnderscores, "keep-underscores", false, "turn leading underscores into \"X\", instead of dropping them")
 	flag.StringVar(&buildTags, "tags", "", "comma-separated list of build tags to apply")
 	flag.BoolVar(&selfGenerate, "generate", false, "generate a go:generate directive in the output file, so future `go generate`s will update the file; require `invoker` in the PATH")
 	flag.BoolVar(&preserveRealArg0, "preserve-arg0", false, "preserve the actual path to `invoker`; will generate machine-specific information and might contain your private information")
-	flag.BoolVar(&lazy, "lazy", false, "favor *windows.LazyProc over *windows.Proc")
+	flag.Var(&includePatterns, "include", "only generate exports matching the regular `expression`; can be repeated")
+	flag.Var(&excludePatterns, "exclude", "do not generate exports matching the regular `expression`; can be repeated")
+	flag.StringVar(&symbolsFileName, "symbols", "", "only generate the exports listed in the `file`, one per line; missing ones are errors")
+	flag.StringVar(&defFileName, "def", "", "module-definition `file` naming the ordinal-only exports of the DLL")
+	flag.StringVar(&headerFileName, "header", "", "C `header` declaring the exports; generates typed wrapper methods, constants and types from it")
+	flag.BoolVar(&skipForwarders, "skip-forwarders", false, "do not generate exports forwarded to other DLLs")
+	flag.BoolVar(&check, "check", false, "do not write the output file; instead, print a diff and exit with 1 if it is not up to date")
+	flag.StringVar(&configFileName, "config", "", "generate all the libraries described in the YAML or JSON manifest `file`, instead of -dll")
+	flag.StringVar(&dumpConfigFileName, "dump-config", "", "do not generate code; instead, write a manifest of the chosen exports to the `file` (\"-\" for stdout)")
+	flag.StringVar(&templateFileName, "template", "", "Go text/template `file` to generate the code with, instead of the built-in one")
+	flag.StringVar(&style, "style", styleStruct, "output `style`: \"struct\" for a struct filled by Unmarshal, or \"lazyvars\" for package-level lazy procs loaded on first use")
+	flag.BoolVar(&lazy, "lazy", false, "favor lazy procs (*windows.LazyProc or *goinvoke.LazyProc) over *windows.Proc or *goinvoke.Proc")
+}
+
+// parseInterspersed parses the flags, which might come after the positional arguments, and returns the latter. "--"
+// ends the flags.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 {
+		err := flags.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+
+		rest := flags.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
 
-	flag.Parse()
+	return positional, nil
 }
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	flags := flag.NewFlagSet("call", flag.ContinueOnError)
+	ret := flags.String("ret", "int", "")
+
+	positional, err := parseInterspersed(flags, []string{"libc.so.6", "strlen", "cstring:hello", "-ret", "uint64"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"libc.so.6", "strlen", "cstring:hello"}, positional)
+	assert.EqualValues(t, "uint64", *ret)
+
+	positional, err = parseInterspersed(flags, []string{"libc.so.6", "--", "strlen", "-ret"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"libc.so.6", "strlen", "-ret"}, positional)
+}
@@ -0,0 +1,245 @@
+//go:build windows || (cgo && (darwin || freebsd || linux)) || ((darwin || linux) && (amd64 || arm64))
+
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/jamesits/goinvoke"
+	"github.com/jamesits/goinvoke/utils"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// callArgument is an argument of `invoker call`, parsed from a "type:value" literal.
+type callArgument struct {
+	Value any    // passed to goinvoke.Call1
+	Out   []byte // out-buffer only, printed after the call
+}
+
+// parseCallArgument parses a "type:value" literal, e.g. "int:-1", "cstring:hello" or "out-buffer:64".
+func parseCallArgument(literal string) (a callArgument, err error) {
+	t, value, ok := strings.Cut(literal, ":")
+	if !ok {
+		return a, fmt.Errorf("invalid argument \"%s\", expecting type:value", literal)
+	}
+
+	switch t {
+	case "int":
+		var v int64
+		v, err = strconv.ParseInt(value, 0, 64)
+		a.Value = v
+	case "uint":
+		var v uint64
+		v, err = strconv.ParseUint(value, 0, 64)
+		a.Value = v
+	case "pointer":
+		var v uint64
+		if value != "null" {
+			v, err = strconv.ParseUint(value, 0, 64)
+		}
+		a.Value = uintptr(v)
+	case "float":
+		var v float64
+		v, err = strconv.ParseFloat(value, 32)
+		a.Value = float32(v)
+	case "double":
+		var v float64
+		v, err = strconv.ParseFloat(value, 64)
+		a.Value = v
+	case "cstring":
+		a.Value = value
+	case "wstring":
+		a.Value = wideString(value)
+	case "out-buffer":
+		var size int
+		size, err = strconv.Atoi(value)
+		if err == nil && size <= 0 {
+			err = errors.New("the size must be positive")
+		}
+		if err == nil {
+			a.Out = make([]byte, size)
+			a.Value = unsafe.Pointer(&a.Out[0])
+		}
+	default:
+		return a, fmt.Errorf("invalid argument \"%s\": unknown type \"%s\"", literal, t)
+	}
+	if err != nil {
+		return a, fmt.Errorf("invalid argument \"%s\": %w", literal, err)
+	}
+
+	return a, nil
+}
+
+// wideString converts s to a zero-terminated wchar_t string, which is UTF-16 on Windows, and UTF-32 elsewhere.
+func wideString(s string) unsafe.Pointer {
+	if wcharSize == 2 {
+		buf := append(utf16.Encode([]rune(s)), 0)
+		return unsafe.Pointer(&buf[0])
+	}
+
+	buf := append([]rune(s), 0)
+	return unsafe.Pointer(&buf[0])
+}
+
+// readWideString copies a zero-terminated wchar_t string.
+func readWideString(ptr uintptr) string {
+	p := *(*unsafe.Pointer)(unsafe.Pointer(&ptr))
+	if wcharSize == 2 {
+		var u []uint16
+		for i := 0; *(*uint16)(unsafe.Add(p, i*2)) != 0; i++ {
+			u = append(u, *(*uint16)(unsafe.Add(p, i*2)))
+		}
+		return string(utf16.Decode(u))
+	}
+
+	var runes []rune
+	for i := 0; *(*rune)(unsafe.Add(p, i*4)) != 0; i++ {
+		runes = append(runes, *(*rune)(unsafe.Add(p, i*4)))
+	}
+	return string(runes)
+}
+
+// returnTypes are the valid values of `invoker call -ret`.
+var returnTypes = []string{"void", "int", "int64", "uint", "uint64", "pointer", "cstring", "wstring", "float", "double"}
+
+// callTyped calls p with args through goinvoke.Call1, with the Go type of the return type t, which must be one of
+// returnTypes, and formats the result.
+func callTyped(p goinvoke.FunctionPointer, args []any, t string) (string, error) {
+	switch t {
+	case "void":
+		_, err := goinvoke.Call1[struct{}](p, args...)
+		return "", err
+	case "int":
+		r, err := goinvoke.Call1[int32](p, args...)
+		return strconv.FormatInt(int64(r), 10), err
+	case "int64":
+		r, err := goinvoke.Call1[int64](p, args...)
+		return strconv.FormatInt(r, 10), err
+	case "uint":
+		r, err := goinvoke.Call1[uint32](p, args...)
+		return strconv.FormatUint(uint64(r), 10), err
+	case "uint64":
+		r, err := goinvoke.Call1[uint64](p, args...)
+		return strconv.FormatUint(r, 10), err
+	case "float":
+		r, err := goinvoke.Call1[float32](p, args...)
+		return strconv.FormatFloat(float64(r), 'g', -1, 32), err
+	case "double":
+		r, err := goinvoke.Call1[float64](p, args...)
+		return strconv.FormatFloat(r, 'g', -1, 64), err
+	default:
+		r, err := goinvoke.Call1[uintptr](p, args...)
+		return formatPointer(t, r), err
+	}
+}
+
+// formatPointer formats a pointer returned by a function returning t, which is pointer, cstring or wstring.
+func formatPointer(t string, r uintptr) string {
+	if r == 0 {
+		return "NULL"
+	}
+	switch t {
+	case "cstring":
+		return strconv.Quote(utils.UintPtrToString(r))
+	case "wstring":
+		return strconv.Quote(readWideString(r))
+	default:
+		return fmt.Sprintf("0x%x", r)
+	}
+}
+
+// callFunction calls p with args, and prints the result, the error code and the out-buffers to w. Types
+// goinvoke.Call1 cannot pass on this platform are errors wrapping goinvoke.ErrorUnsupportedType or
+// goinvoke.ErrorTooManyArguments.
+func callFunction(w io.Writer, p goinvoke.FunctionPointer, args []callArgument, ret string) error {
+	values := make([]any, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+
+	result, err := callTyped(p, values, ret)
+	runtime.KeepAlive(args)
+
+	var errno syscall.Errno
+	if err != nil && !errors.As(err, &errno) {
+		return err
+	}
+
+	if ret != "void" {
+		_, _ = fmt.Fprintf(w, "result: %s\n", result)
+	}
+	_, _ = fmt.Fprintf(w, "%s: %d (%v)\n", errnoName, uintptr(errno), errno)
+	for i, a := range args {
+		if a.Out != nil {
+			_, _ = fmt.Fprintf(w, "argument %d:\n%s", i, hex.Dump(a.Out))
+		}
+	}
+	return nil
+}
+
+// callMain implements `invoker call`, and returns the exit code.
+func callMain(args []string) int {
+	flags := flag.NewFlagSet("call", flag.ExitOnError)
+	ret := flags.String("ret", "int", "return `type`: one of "+strings.Join(returnTypes, ", "))
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s call [-ret type] file.dll function [type:value...]\n\n"+
+			"Calls a function of a library, and prints its result, followed by errno (or GetLastError() on Windows), which is\n"+
+			"only meaningful if the result says so. Arguments are int, uint, pointer (or \"pointer:null\"), float, double,\n"+
+			"cstring, wstring, or out-buffer:<size> (a zeroed buffer, printed after the call), e.g. \"cstring:hello\".\n"+
+			"float and double only work on amd64 and arm64, on Linux only when built with CGO_ENABLED=0, and cannot be\n"+
+			"mixed with other arguments.\n\n", selfExecutableName)
+		flags.PrintDefaults()
+	}
+	positional, _ := parseInterspersed(flags, args)
+	if len(positional) < 2 {
+		flags.Usage()
+		return 64
+	}
+
+	validReturnType := false
+	for _, t := range returnTypes {
+		validReturnType = validReturnType || t == *ret
+	}
+	if !validReturnType {
+		log.Printf("unknown return type \"%s\"", *ret)
+		return 64
+	}
+
+	var callArgs []callArgument
+	for _, literal := range positional[2:] {
+		a, err := parseCallArgument(literal)
+		if err != nil {
+			log.Print(err)
+			return 64
+		}
+		callArgs = append(callArgs, a)
+	}
+
+	p, release, code, err := findProc(positional[0], positional[1])
+	if err != nil {
+		log.Print(err)
+		return code
+	}
+	defer release()
+
+	err = callFunction(os.Stdout, p, callArgs, *ret)
+	if errors.Is(err, goinvoke.ErrorUnsupportedType) || errors.Is(err, goinvoke.ErrorTooManyArguments) {
+		log.Printf("unable to call \"%s\": %v\n", positional[1], err)
+		return 64
+	}
+	if err != nil {
+		log.Printf("unable to call \"%s\": %v\n", positional[1], err)
+		return 70
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+//go:build linux && (cgo || amd64 || arm64)
+
+package main
+
+import (
+	"bytes"
+	"github.com/jamesits/goinvoke"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCallFunction(t *testing.T) {
+	call := func(function string, ret string, literals ...string) string {
+		p, release, _, err := findProc("libc.so.6", function)
+		assert.NoError(t, err)
+		defer release()
+
+		var args []callArgument
+		for _, literal := range literals {
+			a, err := parseCallArgument(literal)
+			assert.NoError(t, err)
+			args = append(args, a)
+		}
+
+		var b bytes.Buffer
+		assert.NoError(t, callFunction(&b, p, args, ret))
+		return b.String()
+	}
+
+	assert.True(t, strings.HasPrefix(call("strlen", "int", "cstring:hello"), "result: 5\nerrno: "))
+	assert.True(t, strings.HasPrefix(call("abs", "int", "int:-42"), "result: 42\n"))
+	assert.True(t, strings.HasPrefix(call("strchr", "cstring", "cstring:hello, world", "int:0x77"), "result: \"world\"\n"))
+	assert.True(t, strings.HasPrefix(call("strchr", "cstring", "cstring:hello", "int:0x77"), "result: NULL\n"))
+	assert.True(t, strings.HasPrefix(call("wcslen", "uint64", "wstring:héllo"), "result: 5\n"))
+
+	// errno is set by a failing call
+	assert.True(t, strings.HasPrefix(call("open", "int", "cstring:/nonexistent", "int:0"), "result: -1\nerrno: 2 (no such file or directory)\n"))
+
+	out := call("snprintf", "int", "out-buffer:8", "uint:8", "cstring:%d", "int:42")
+	assert.Contains(t, out, "argument 0:\n00000000  34 32 00 00 00 00 00 00")
+
+	// sign extension of C int results
+	assert.True(t, strings.HasPrefix(call("abs", "int64", "int:-42"), "result: 42\n"))
+
+	// calls goinvoke.Call1 cannot make are errors
+	p, release, _, err := findProc("libm.so.6", "ldexp")
+	assert.NoError(t, err)
+	defer release()
+	mixed := []callArgument{{Value: 1.5}, {Value: int64(3)}}
+	assert.ErrorIs(t, callFunction(io.Discard, p, mixed, "double"), goinvoke.ErrorUnsupportedType)
+	tooMany := make([]callArgument, 43)
+	for i := range tooMany {
+		tooMany[i] = callArgument{Value: int64(i)}
+	}
+	assert.ErrorIs(t, callFunction(io.Discard, p, tooMany, "int"), goinvoke.ErrorTooManyArguments)
+
+	_, _, code, err := findProc("libc.so.6", "no_such_function")
+	assert.Error(t, err)
+	assert.EqualValues(t, 65, code)
+}
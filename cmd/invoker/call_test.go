@@ -0,0 +1,52 @@
+//go:build windows || (cgo && (darwin || freebsd || linux)) || ((darwin || linux) && (amd64 || arm64))
+
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"unsafe"
+)
+
+func TestParseCallArgument(t *testing.T) {
+	a, err := parseCallArgument("int:-1")
+	assert.NoError(t, err)
+	assert.EqualValues(t, int64(-1), a.Value)
+
+	a, err = parseCallArgument("uint:0x10")
+	assert.NoError(t, err)
+	assert.EqualValues(t, uint64(16), a.Value)
+
+	a, err = parseCallArgument("pointer:null")
+	assert.NoError(t, err)
+	assert.EqualValues(t, uintptr(0), a.Value)
+
+	a, err = parseCallArgument("float:1.5")
+	assert.NoError(t, err)
+	assert.EqualValues(t, float32(1.5), a.Value)
+
+	a, err = parseCallArgument("double:1.5")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1.5, a.Value)
+
+	// the value might contain colons
+	a, err = parseCallArgument("cstring:a:b")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "a:b", a.Value)
+
+	a, err = parseCallArgument("out-buffer:4")
+	assert.NoError(t, err)
+	assert.Len(t, a.Out, 4)
+	assert.EqualValues(t, unsafe.Pointer(&a.Out[0]), a.Value)
+
+	for _, literal := range []string{"42", "int:x", "out-buffer:0", "struct:{}"} {
+		_, err = parseCallArgument(literal)
+		assert.Error(t, err, literal)
+	}
+}
+
+func TestFormatPointer(t *testing.T) {
+	assert.EqualValues(t, "NULL", formatPointer("cstring", 0))
+	assert.EqualValues(t, "NULL", formatPointer("pointer", 0))
+	assert.EqualValues(t, "0x1000", formatPointer("pointer", 0x1000))
+}
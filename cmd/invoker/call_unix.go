@@ -0,0 +1,30 @@
+//go:build (cgo && (darwin || freebsd || linux)) || ((darwin || linux) && (amd64 || arm64))
+
+package main
+
+import (
+	"fmt"
+	"github.com/jamesits/goinvoke"
+)
+
+// wchar_t is UTF-32 on Linux, macOS and the BSDs
+const wcharSize = 4
+
+// errnoName is what `invoker call` calls the error code of the function.
+const errnoName = "errno"
+
+// findProc loads the library with dlopen(3), and finds the function in it. The library is unloaded by release.
+func findProc(library, function string) (p goinvoke.FunctionPointer, release func(), code int, err error) {
+	dll, err := goinvoke.LoadDLL(library)
+	if err != nil {
+		return nil, nil, 66, fmt.Errorf("unable to load \"%s\": %w", library, err)
+	}
+
+	proc, err := dll.FindProc(function)
+	if err != nil {
+		_ = dll.Release()
+		return nil, nil, 65, fmt.Errorf("unable to find \"%s\": %w", function, err)
+	}
+
+	return proc, func() { _ = dll.Release() }, 0, nil
+}
@@ -0,0 +1,13 @@
+//go:build !(windows || (cgo && (darwin || freebsd || linux)) || ((darwin || linux) && (amd64 || arm64)))
+
+package main
+
+import (
+	"log"
+)
+
+// callMain implements `invoker call` where purego cannot call functions, and returns the exit code.
+func callMain([]string) int {
+	log.Print("call is not supported on this platform")
+	return 69
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"github.com/jamesits/goinvoke"
+	"github.com/jamesits/goinvoke/utils"
+	"golang.org/x/sys/windows"
+	"strconv"
+	"strings"
+)
+
+// wchar_t is UTF-16 on Windows
+const wcharSize = 2
+
+// errnoName is what `invoker call` calls the error code of the function.
+const errnoName = "last error"
+
+// findProc loads the library, and finds the function in it, by name or by ordinal ("@<ordinal>"). Like goinvoke.Unmarshal
+// does, bare names are only searched for in System32. The library is unloaded by release.
+func findProc(library, function string) (p goinvoke.FunctionPointer, release func(), code int, err error) {
+	var dll *windows.DLL
+	if utils.IsImplicitRelativePath(library) {
+		var h windows.Handle
+		h, err = windows.LoadLibraryEx(library, 0, windows.LOAD_LIBRARY_SEARCH_SYSTEM32)
+		dll = &windows.DLL{Name: library, Handle: h}
+	} else {
+		dll, err = windows.LoadDLL(library)
+	}
+	if err != nil {
+		return nil, nil, 66, fmt.Errorf("unable to load \"%s\": %w", library, err)
+	}
+
+	var proc *windows.Proc
+	if ordinal, ok := strings.CutPrefix(function, "@"); ok {
+		var n uint64
+		n, err = strconv.ParseUint(ordinal, 10, 16)
+		if err == nil {
+			proc, err = dll.FindProcByOrdinal(uintptr(n))
+		}
+	} else {
+		proc, err = dll.FindProc(function)
+	}
+	if err != nil {
+		_ = dll.Release()
+		return nil, nil, 65, fmt.Errorf("unable to find \"%s\": %w", function, err)
+	}
+
+	return proc, func() { _ = dll.Release() }, 0, nil
+}
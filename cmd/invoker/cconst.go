@@ -0,0 +1,275 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// cValue is the value of an integer constant expression.
+type cValue struct {
+	value    int64
+	unsigned bool
+}
+
+// evaluate evaluates an integer constant expression, e.g. the value of a macro or an enumerator.
+func (h *cHeader) evaluate(tokens []cToken) (cValue, bool) {
+	e := &cEvaluator{h: h, tokens: h.expand(tokens, map[string]bool{})}
+	v, ok := e.expression(0)
+	if !ok || e.pos != len(e.tokens) {
+		return cValue{}, false
+	}
+	return v, true
+}
+
+type cEvaluator struct {
+	h      *cHeader
+	tokens []cToken
+	pos    int
+}
+
+// binary operators and their precedence
+var cBinaryOperators = map[string]int{
+	"||": 1, "&&": 2, "|": 3, "^": 4, "&": 5,
+	"==": 6, "!=": 6, "<": 7, ">": 7, "<=": 7, ">=": 7,
+	"<<": 8, ">>": 8, "+": 9, "-": 9, "*": 10, "/": 10, "%": 10,
+}
+
+func (e *cEvaluator) peek() string {
+	if e.pos < len(e.tokens) {
+		return e.tokens[e.pos].Text
+	}
+	return ""
+}
+
+func (e *cEvaluator) expression(minPrecedence int) (cValue, bool) {
+	left, ok := e.unary()
+	if !ok {
+		return left, false
+	}
+
+	for {
+		op := e.peek()
+		precedence, isOperator := cBinaryOperators[op]
+		if !isOperator || precedence <= minPrecedence {
+			return left, true
+		}
+		e.pos++
+
+		right, ok := e.expression(precedence)
+		if !ok {
+			return left, false
+		}
+		left, ok = binaryOperation(op, left, right)
+		if !ok {
+			return left, false
+		}
+	}
+}
+
+func (e *cEvaluator) unary() (cValue, bool) {
+	if e.pos >= len(e.tokens) {
+		return cValue{}, false
+	}
+	t := e.tokens[e.pos]
+	e.pos++
+
+	switch {
+	case t.Text == "-" || t.Text == "+" || t.Text == "~" || t.Text == "!":
+		v, ok := e.unary()
+		switch t.Text {
+		case "-":
+			v.value = -v.value
+		case "~":
+			v.value = ^v.value
+		case "!":
+			v = cValue{value: boolToInt64(v.value == 0)}
+		}
+		return v.truncate(), ok
+	case t.Text == "(":
+		// casts are ignored, except for the signedness
+		if e.pos < len(e.tokens) && e.h.isTypeStart(e.tokens[e.pos]) {
+			end := e.pos
+			unsigned := false
+			for end < len(e.tokens) && e.tokens[end].Text != ")" {
+				unsigned = unsigned || e.tokens[end].Text == "unsigned" || strings.HasPrefix(cScalarTypes[e.tokens[end].Text], "uint")
+				end++
+			}
+			e.pos = end + 1
+			v, ok := e.unary()
+			v.unsigned = unsigned
+			return v.truncate(), ok
+		}
+		v, ok := e.expression(0)
+		if !ok || e.peek() != ")" {
+			return v, false
+		}
+		e.pos++
+		return v, true
+	case t.Kind == cTokenNumber:
+		return parseCInteger(t.Text)
+	case t.Kind == cTokenChar:
+		s, err := strconv.Unquote(t.Text)
+		if err != nil || len(s) != 1 {
+			return cValue{}, false
+		}
+		return cValue{value: int64(s[0])}, true
+	case t.Kind == cTokenIdent:
+		v, ok := e.h.values[t.Text]
+		return cValue{value: v}, ok
+	default:
+		return cValue{}, false
+	}
+}
+
+func boolToInt64(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// truncate makes an unsigned value wrap around like a 32-bit unsigned int.
+func (v cValue) truncate() cValue {
+	if v.unsigned {
+		v.value = int64(uint32(v.value))
+	}
+	return v
+}
+
+func binaryOperation(op string, l, r cValue) (cValue, bool) {
+	v := cValue{unsigned: l.unsigned || r.unsigned}
+	switch op {
+	case "||":
+		v = cValue{value: boolToInt64(l.value != 0 || r.value != 0)}
+	case "&&":
+		v = cValue{value: boolToInt64(l.value != 0 && r.value != 0)}
+	case "|":
+		v.value = l.value | r.value
+	case "^":
+		v.value = l.value ^ r.value
+	case "&":
+		v.value = l.value & r.value
+	case "==":
+		v = cValue{value: boolToInt64(l.value == r.value)}
+	case "!=":
+		v = cValue{value: boolToInt64(l.value != r.value)}
+	case "<":
+		v = cValue{value: boolToInt64(l.value < r.value)}
+	case ">":
+		v = cValue{value: boolToInt64(l.value > r.value)}
+	case "<=":
+		v = cValue{value: boolToInt64(l.value <= r.value)}
+	case ">=":
+		v = cValue{value: boolToInt64(l.value >= r.value)}
+	case "<<":
+		v.value = l.value << r.value
+	case ">>":
+		v.value = l.value >> r.value
+	case "+":
+		v.value = l.value + r.value
+	case "-":
+		v.value = l.value - r.value
+	case "*":
+		v.value = l.value * r.value
+	case "/", "%":
+		if r.value == 0 {
+			return v, false
+		}
+		if op == "/" {
+			v.value = l.value / r.value
+		} else {
+			v.value = l.value % r.value
+		}
+	}
+	return v.truncate(), true
+}
+
+// parseCInteger parses an integer literal with an optional suffix, like "0x10UL".
+func parseCInteger(s string) (cValue, bool) {
+	v := cValue{}
+	lower := strings.ToLower(s)
+	long := false
+	for strings.HasSuffix(lower, "u") || strings.HasSuffix(lower, "l") {
+		if strings.HasSuffix(lower, "u") {
+			v.unsigned = true
+		} else {
+			long = true
+		}
+		lower = lower[:len(lower)-1]
+	}
+
+	base := 10
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		base = 16
+		lower = lower[2:]
+	case strings.HasPrefix(lower, "0b"):
+		base = 2
+		lower = lower[2:]
+	case len(lower) > 1 && lower[0] == '0':
+		base = 8
+		lower = lower[1:]
+	}
+
+	u, err := strconv.ParseUint(lower, base, 64)
+	if err != nil {
+		return v, false
+	}
+	v.value = int64(u)
+	// only plain unsigned ints wrap around at 32 bits
+	if long || u > 0xffffffff {
+		v.unsigned = false
+	}
+	return v, true
+}
+
+// macroConstant converts the body of an object-like macro into a Go constant expression, if it is a constant.
+func (h *cHeader) macroConstant(body []cToken) (value string, ok bool) {
+	tokens := h.expand(body, map[string]bool{})
+	if len(tokens) == 0 {
+		return "", false
+	}
+
+	// strings, including adjacent ones
+	if tokens[0].Kind == cTokenString {
+		var b strings.Builder
+		for _, t := range tokens {
+			if t.Kind != cTokenString {
+				return "", false
+			}
+			s, err := strconv.Unquote(t.Text)
+			if err != nil {
+				return "", false
+			}
+			b.WriteString(s)
+		}
+		return strconv.Quote(b.String()), true
+	}
+
+	// floating point numbers
+	inner := tokens
+	for len(inner) >= 3 && inner[0].Text == "(" && inner[len(inner)-1].Text == ")" {
+		inner = inner[1 : len(inner)-1]
+	}
+	sign := ""
+	if len(inner) == 2 && inner[0].Text == "-" {
+		sign = "-"
+		inner = inner[1:]
+	}
+	if len(inner) == 1 && inner[0].Kind == cTokenNumber && !strings.HasPrefix(strings.ToLower(inner[0].Text), "0x") &&
+		strings.ContainsAny(inner[0].Text, ".eE") {
+		f := strings.TrimRight(inner[0].Text, "fFlL")
+		if _, err := strconv.ParseFloat(f, 64); err == nil {
+			return sign + f, true
+		}
+	}
+
+	v, ok := h.evaluate(body)
+	if !ok {
+		return "", false
+	}
+	if len(body) == 1 && strings.HasPrefix(strings.ToLower(body[0].Text), "0x") {
+		return "0x" + strconv.FormatUint(uint64(v.value), 16), true
+	}
+	return strconv.FormatInt(v.value, 10), true
+}
@@ -0,0 +1,1067 @@
+package main
+
+// A best-effort C header parser. It understands enough of C to extract function prototypes, typedefs, structs, enums
+// and constant macros from a typical library header, and warns about anything it does not understand.
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	cTokenIdent = iota
+	cTokenNumber
+	cTokenString
+	cTokenChar
+	cTokenPunct
+)
+
+type cToken struct {
+	Kind int
+	Text string
+}
+
+// cType is a C type as written in a declaration.
+type cType struct {
+	Base     string   // canonical base type, e.g. "unsigned int", "struct foo" or a typedef name
+	Const    bool     // whether the base type is const-qualified
+	Pointers int      // levels of indirection
+	Array    []string // array dimensions, outermost first
+	Func     bool     // whether this is a (pointer to) function
+}
+
+type cParam struct {
+	Name string
+	Type cType
+}
+
+type cFunction struct {
+	Name      string
+	Return    cType
+	Params    []cParam
+	Variadic  bool
+	Prototype string
+}
+
+type cField struct {
+	Name string
+	Type cType
+}
+
+type cStruct struct {
+	Name   string // typedef name or tag
+	Tag    string
+	Fields []cField
+	Opaque bool
+
+	key string // canonical type name
+}
+
+type cEnumerator struct {
+	Name  string
+	Value int64
+}
+
+type cEnum struct {
+	Name        string // typedef name or tag; empty for anonymous enums
+	Tag         string
+	Enumerators []cEnumerator
+}
+
+type cTypedef struct {
+	Name string
+	Type cType
+}
+
+type cMacro struct {
+	Name     string
+	Params   []string // for function-like macros
+	Function bool
+	Body     []cToken
+}
+
+// cHeader contains all the declarations understood from a C header, in order of appearance.
+type cHeader struct {
+	Macros    []*cMacro
+	Typedefs  []cTypedef
+	Structs   []*cStruct
+	Enums     []*cEnum
+	Functions []cFunction
+
+	macros   map[string]*cMacro
+	typedefs map[string]cType
+	structs  map[string]*cStruct // by canonical type name, e.g. "struct foo"
+	enums    map[string]*cEnum   // by canonical type name, e.g. "enum foo"
+	values   map[string]int64    // enumerators
+	included map[string]bool     // paths of headers already read
+}
+
+// cKeywords are specifiers which form the base type.
+var cTypeKeywords = map[string]bool{
+	"void": true, "char": true, "short": true, "int": true, "long": true, "float": true, "double": true,
+	"signed": true, "unsigned": true, "_Bool": true, "bool": true,
+}
+
+// cDecorations are tokens that do not affect the type, including common calling conventions and export macros.
+var cDecorations = map[string]bool{
+	"const": true, "volatile": true, "extern": true, "static": true, "inline": true, "__inline": true,
+	"__inline__": true, "register": true, "restrict": true, "__restrict": true, "__restrict__": true,
+	"__extension__": true, "__cdecl": true, "__stdcall": true, "__fastcall": true, "__vectorcall": true,
+	"_cdecl": true, "_stdcall": true, "WINAPI": true, "WINAPIV": true, "APIENTRY": true, "CALLBACK": true,
+	"STDAPICALLTYPE": true, "NTAPI": true, "__out": true, "__in": true, "_In_": true, "_Out_": true,
+	"_Inout_": true, "_In_opt_": true, "_Out_opt_": true, "_Inout_opt_": true,
+}
+
+// cDecorationFunctions are decorations taking arguments, like __declspec(dllimport).
+var cDecorationFunctions = map[string]bool{
+	"__declspec": true, "__attribute__": true, "__attribute": true, "_Pragma": true, "__asm__": true,
+	"__asm": true, "_Alignas": true, "alignas": true,
+}
+
+// cPredefinedMacros are the macros a compiler targeting each OS defines, so that the header is parsed as if it were
+// compiled for it.
+var cPredefinedMacros = map[string][]string{
+	"windows": {"_WIN32", "_WIN64", "WIN32", "_WINDOWS"},
+	"linux":   {"__linux__", "__linux", "linux", "__unix__", "__unix", "unix", "__ELF__"},
+	"freebsd": {"__FreeBSD__", "__unix__", "__unix", "unix", "__ELF__"},
+	"darwin":  {"__APPLE__", "__MACH__"},
+}
+
+// parseCHeaderFile parses the C header at path for goos, including the headers it includes with quotes (e.g.
+// `#include "zconf.h"`) from the same directory.
+func parseCHeaderFile(path string, goos string) (*cHeader, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	h := newCHeader(goos)
+	h.included[filepath.Clean(path)] = true
+	h.parse(h.preprocess(string(src), filepath.Dir(path)))
+	return h, nil
+}
+
+// parseCHeader parses C source code for goos.
+func parseCHeader(src string, goos string) *cHeader {
+	h := newCHeader(goos)
+	h.parse(h.preprocess(src, ""))
+	return h
+}
+
+func newCHeader(goos string) *cHeader {
+	h := &cHeader{
+		macros:   map[string]*cMacro{},
+		typedefs: map[string]cType{},
+		structs:  map[string]*cStruct{},
+		enums:    map[string]*cEnum{},
+		values:   map[string]int64{},
+		included: map[string]bool{},
+	}
+
+	for _, name := range cPredefinedMacros[goos] {
+		h.macros[name] = &cMacro{Name: name, Body: []cToken{{Kind: cTokenNumber, Text: "1"}}}
+	}
+	h.macros["__STDC__"] = &cMacro{Name: "__STDC__", Body: []cToken{{Kind: cTokenNumber, Text: "1"}}}
+	return h
+}
+
+// parse parses preprocessed code.
+func (h *cHeader) parse(code string) {
+	tokens := h.expand(tokenizeC(code), map[string]bool{})
+
+	// split into top level declarations
+	var decl []cToken
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		// extern "C" { ... }
+		if depth == 0 && len(decl) == 0 && t.Text == "extern" && i+2 < len(tokens) && tokens[i+1].Kind == cTokenString && tokens[i+2].Text == "{" {
+			i += 2
+			continue
+		}
+		if depth == 0 && len(decl) == 0 && t.Text == "}" {
+			continue
+		}
+
+		switch t.Text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		}
+		decl = append(decl, t)
+
+		// function definitions (e.g. static inline functions) end with a body instead of a semicolon
+		if depth == 0 && t.Text == "}" && len(decl) >= 2 && isFunctionBody(decl) {
+			decl = nil
+			continue
+		}
+		if depth == 0 && t.Text == ";" {
+			h.parseDeclaration(decl[:len(decl)-1])
+			decl = nil
+		}
+	}
+}
+
+// isFunctionBody tests if a declaration ending with "}" is a function definition.
+func isFunctionBody(decl []cToken) bool {
+	depth := 0
+	for i := len(decl) - 1; i > 0; i-- {
+		switch decl[i].Text {
+		case "}":
+			depth++
+		case "{":
+			depth--
+			if depth == 0 {
+				return decl[i-1].Text == ")"
+			}
+		}
+	}
+	return false
+}
+
+// preprocess removes comments, handles directives, and returns the remaining code. Quoted includes are looked up in
+// dir, unless it is empty.
+func (h *cHeader) preprocess(src string, dir string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\\\n", "")
+	src = stripCComments(src)
+
+	// conditionals
+	type branch struct {
+		active bool // whether the current branch is active
+		taken  bool // whether any branch so far has been active
+		parent bool // whether the enclosing branch is active
+	}
+	var stack []branch
+	active := func() bool {
+		return len(stack) == 0 || stack[len(stack)-1].active
+	}
+
+	var code strings.Builder
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") {
+			if active() {
+				code.WriteString(line)
+			}
+			code.WriteByte('\n')
+			continue
+		}
+
+		directive := strings.TrimSpace(trimmed[1:])
+		keywordEnd := strings.IndexFunc(directive, func(r rune) bool { return !isCIdentRune(r) })
+		if keywordEnd < 0 {
+			keywordEnd = len(directive)
+		}
+		keyword, rest := directive[:keywordEnd], strings.TrimSpace(directive[keywordEnd:])
+
+		switch keyword {
+		case "if", "ifdef", "ifndef":
+			b := branch{parent: active()}
+			if b.parent {
+				switch keyword {
+				case "if":
+					b.active = h.condition(rest)
+				case "ifdef":
+					_, b.active = h.macros[rest]
+				case "ifndef":
+					_, defined := h.macros[rest]
+					b.active = !defined
+				}
+			}
+			b.taken = b.active
+			stack = append(stack, b)
+			continue
+		case "elif", "else":
+			if len(stack) == 0 {
+				continue
+			}
+			b := &stack[len(stack)-1]
+			b.active = b.parent && !b.taken && (keyword == "else" || h.condition(rest))
+			b.taken = b.taken || b.active
+			continue
+		case "endif":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		if !active() {
+			continue
+		}
+
+		switch keyword {
+		case "include":
+			if dir != "" && len(rest) > 2 && rest[0] == '"' && rest[len(rest)-1] == '"' {
+				path := filepath.Clean(filepath.Join(dir, rest[1:len(rest)-1]))
+				if src, err := os.ReadFile(path); err == nil && !h.included[path] {
+					h.included[path] = true
+					code.WriteString(h.preprocess(string(src), filepath.Dir(path)))
+				}
+			}
+		case "undef":
+			delete(h.macros, rest)
+		case "define":
+			h.define(rest)
+		}
+	}
+
+	return code.String()
+}
+
+// define handles a #define directive.
+func (h *cHeader) define(directive string) {
+	nameEnd := strings.IndexFunc(directive, func(r rune) bool { return !isCIdentRune(r) })
+	if nameEnd < 0 {
+		nameEnd = len(directive)
+	}
+	name := directive[:nameEnd]
+	if name == "" {
+		return
+	}
+
+	macro := &cMacro{Name: name, Body: tokenizeC(directive[nameEnd:])}
+	if nameEnd < len(directive) && directive[nameEnd] == '(' {
+		// function-like macros are supported as long as they only substitute their parameters
+		end := matchingParen(macro.Body, 0)
+		if end < 0 {
+			return
+		}
+		macro.Function = true
+		for _, t := range macro.Body[1:end] {
+			if t.Text == "..." || t.Text == "#" || t.Text == "##" {
+				macro.Function = false
+			} else if t.Kind == cTokenIdent {
+				macro.Params = append(macro.Params, t.Text)
+			}
+		}
+		macro.Body = macro.Body[end+1:]
+		for _, t := range macro.Body {
+			if t.Text == "#" || t.Text == "##" {
+				macro.Function = false
+			}
+		}
+		if !macro.Function {
+			log.Printf("warning: function-like macro \"%s\" is not supported", name)
+			return
+		}
+	}
+
+	h.macros[name] = macro
+	if !macro.Function {
+		h.Macros = append(h.Macros, macro)
+	}
+}
+
+// condition evaluates the condition of #if or #elif. Undefined identifiers are 0, like in the C preprocessor.
+func (h *cHeader) condition(expr string) bool {
+	tokens := tokenizeC(expr)
+
+	// defined X, defined(X)
+	var replaced []cToken
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Text != "defined" {
+			replaced = append(replaced, tokens[i])
+			continue
+		}
+		name := ""
+		if i+1 < len(tokens) && tokens[i+1].Kind == cTokenIdent {
+			name = tokens[i+1].Text
+			i++
+		} else if i+3 < len(tokens) && tokens[i+1].Text == "(" && tokens[i+3].Text == ")" {
+			name = tokens[i+2].Text
+			i += 3
+		}
+		_, defined := h.macros[name]
+		replaced = append(replaced, cToken{Kind: cTokenNumber, Text: strconv.FormatInt(boolToInt64(defined), 10)})
+	}
+
+	replaced = h.expand(replaced, map[string]bool{})
+	for i, t := range replaced {
+		if t.Kind == cTokenIdent {
+			replaced[i] = cToken{Kind: cTokenNumber, Text: "0"}
+		}
+	}
+
+	v, ok := h.evaluate(replaced)
+	return ok && v.value != 0
+}
+
+// expand replaces macros in tokens.
+func (h *cHeader) expand(tokens []cToken, expanding map[string]bool) (ret []cToken) {
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		macro, ok := h.macros[t.Text]
+		if t.Kind != cTokenIdent || !ok || expanding[t.Text] {
+			ret = append(ret, t)
+			continue
+		}
+
+		body := macro.Body
+		if macro.Function {
+			// a function-like macro name not followed by arguments is just an identifier
+			if i+1 >= len(tokens) || tokens[i+1].Text != "(" {
+				ret = append(ret, t)
+				continue
+			}
+			end := matchingParen(tokens, i+1)
+			if end < 0 {
+				ret = append(ret, t)
+				continue
+			}
+
+			args := map[string][]cToken{}
+			for j, arg := range splitTopLevel(tokens[i+2:end], ",") {
+				if j < len(macro.Params) {
+					args[macro.Params[j]] = h.expand(arg, expanding)
+				}
+			}
+			body = nil
+			for _, b := range macro.Body {
+				if arg, ok := args[b.Text]; ok && b.Kind == cTokenIdent {
+					body = append(body, arg...)
+				} else {
+					body = append(body, b)
+				}
+			}
+			i = end
+		}
+
+		expanding[t.Text] = true
+		ret = append(ret, h.expand(body, expanding)...)
+		delete(expanding, t.Text)
+	}
+	return
+}
+
+func stripCComments(src string) string {
+	var b strings.Builder
+	for i := 0; i < len(src); i++ {
+		switch {
+		case src[i] == '"' || src[i] == '\'':
+			// skip string and char literals
+			quote := src[i]
+			b.WriteByte(src[i])
+			for i++; i < len(src) && src[i] != quote && src[i] != '\n'; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					b.WriteByte(src[i])
+					i++
+				}
+				b.WriteByte(src[i])
+			}
+			if i < len(src) {
+				b.WriteByte(src[i])
+			}
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			b.WriteByte('\n')
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			// keep line breaks so that directives stay on their own lines
+			b.WriteString(strings.Repeat("\n", strings.Count(src[i:i+2+end], "\n")))
+			b.WriteByte(' ')
+			i += end + 3
+		default:
+			b.WriteByte(src[i])
+		}
+	}
+	return b.String()
+}
+
+func isCIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+var cPunctuators = []string{"...", "<<", ">>", "->", "&&", "||", "==", "!=", "<=", ">=", "##"}
+
+// tokenizeC splits C code into tokens.
+func tokenizeC(src string) (ret []cToken) {
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case isCIdentRune(c) && !unicode.IsDigit(c):
+			j := i
+			for j < len(src) && isCIdentRune(rune(src[j])) {
+				j++
+			}
+			// wide and UTF-8 string prefixes
+			if j < len(src) && (src[j] == '"' || src[j] == '\'') && (src[i:j] == "L" || src[i:j] == "u8" || src[i:j] == "u" || src[i:j] == "U") {
+				i = j
+				continue
+			}
+			ret = append(ret, cToken{Kind: cTokenIdent, Text: src[i:j]})
+			i = j
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			j := i
+			for j < len(src) && (isCIdentRune(rune(src[j])) || src[j] == '.' ||
+				((src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E' || src[j-1] == 'p' || src[j-1] == 'P') && !strings.HasPrefix(strings.ToLower(src[i:j]), "0x"))) {
+				j++
+			}
+			ret = append(ret, cToken{Kind: cTokenNumber, Text: src[i:j]})
+			i = j
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != src[i] && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(src) {
+				j++
+			}
+			kind := cTokenString
+			if c == '\'' {
+				kind = cTokenChar
+			}
+			if j > len(src) {
+				j = len(src)
+			}
+			ret = append(ret, cToken{Kind: kind, Text: src[i:j]})
+			i = j
+		default:
+			text := string(c)
+			for _, p := range cPunctuators {
+				if strings.HasPrefix(src[i:], p) {
+					text = p
+					break
+				}
+			}
+			ret = append(ret, cToken{Kind: cTokenPunct, Text: text})
+			i += len(text)
+		}
+	}
+	return
+}
+
+// parseDeclaration parses a top level declaration without the trailing semicolon.
+func (h *cHeader) parseDeclaration(decl []cToken) {
+	decl = stripDecorationFunctions(decl)
+	if len(decl) == 0 {
+		return
+	}
+
+	isTypedef := false
+	if decl[0].Text == "typedef" {
+		isTypedef = true
+		decl = decl[1:]
+	}
+
+	base, rest, ok := h.parseSpecifiers(decl)
+	if !ok {
+		return
+	}
+
+	for _, declarator := range splitTopLevel(rest, ",") {
+		if len(declarator) == 0 {
+			continue
+		}
+
+		name, t, params, variadic, ok := h.parseDeclarator(base, declarator)
+		if !ok || name == "" {
+			continue
+		}
+
+		switch {
+		case isTypedef:
+			h.addTypedef(name, t)
+		case t.Func && params != nil:
+			// a function prototype, as opposed to a function pointer variable
+			f := cFunction{
+				Name:      name,
+				Return:    t,
+				Params:    params,
+				Variadic:  variadic,
+				Prototype: formatCTokens(stripDecorations(decl)) + ";",
+			}
+			f.Return.Func = false
+			h.Functions = append(h.Functions, f)
+		default:
+			// variables are not supported
+		}
+	}
+}
+
+func (h *cHeader) addTypedef(name string, t cType) {
+	if _, ok := h.typedefs[name]; ok {
+		return
+	}
+	h.typedefs[name] = t
+
+	// structs and enums are named after their typedef, instead of getting an alias
+	if t.Pointers == 0 && len(t.Array) == 0 && !t.Func {
+		if s, ok := h.structs[t.Base]; ok && (s.Name == "" || s.Name == s.Tag) {
+			s.Name = name
+			return
+		}
+		if e, ok := h.enums[t.Base]; ok && (e.Name == "" || e.Name == e.Tag) {
+			e.Name = name
+			return
+		}
+	}
+
+	h.Typedefs = append(h.Typedefs, cTypedef{Name: name, Type: t})
+}
+
+// stripDecorations removes decorations except const.
+func stripDecorations(tokens []cToken) (ret []cToken) {
+	for _, t := range tokens {
+		if t.Text == "const" || !cDecorations[t.Text] {
+			ret = append(ret, t)
+		}
+	}
+	return
+}
+
+// stripDecorationFunctions removes things like __declspec(dllimport) and __attribute__((...)).
+func stripDecorationFunctions(tokens []cToken) (ret []cToken) {
+	for i := 0; i < len(tokens); i++ {
+		if cDecorationFunctions[tokens[i].Text] && i+1 < len(tokens) && tokens[i+1].Text == "(" {
+			depth := 0
+			for i++; i < len(tokens); i++ {
+				if tokens[i].Text == "(" {
+					depth++
+				} else if tokens[i].Text == ")" {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			continue
+		}
+		ret = append(ret, tokens[i])
+	}
+	return
+}
+
+// splitTopLevel splits tokens by sep, ignoring separators inside brackets.
+func splitTopLevel(tokens []cToken, sep string) (ret [][]cToken) {
+	depth := 0
+	start := 0
+	for i, t := range tokens {
+		switch t.Text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case sep:
+			if depth == 0 {
+				ret = append(ret, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(ret, tokens[start:])
+}
+
+// parseSpecifiers parses the declaration specifiers at the start of decl, and returns the remaining declarators.
+func (h *cHeader) parseSpecifiers(decl []cToken) (base cType, rest []cToken, ok bool) {
+	var keywords []string
+	var unknown []string
+
+	i := 0
+loop:
+	for ; i < len(decl); i++ {
+		t := decl[i]
+		switch {
+		case t.Text == "*" || t.Text == "(" || t.Text == "[" || t.Text == "," || t.Text == ":" || t.Text == "=":
+			break loop
+		case t.Text == "const":
+			base.Const = true
+		case cDecorations[t.Text]:
+		case cTypeKeywords[t.Text]:
+			keywords = append(keywords, t.Text)
+		case t.Text == "struct" || t.Text == "union" || t.Text == "enum":
+			var consumed int
+			base.Base, consumed, ok = h.parseTagged(decl[i:])
+			if !ok {
+				return base, nil, false
+			}
+			i += consumed - 1
+		case t.Kind == cTokenIdent:
+			// an identifier right before the end of a declarator is the name, once we have seen a type
+			if (base.Base != "" || len(keywords) > 0 || len(unknown) > 0) &&
+				(i+1 == len(decl) || strings.Contains("([,:=)", decl[i+1].Text)) {
+				break loop
+			}
+			unknown = append(unknown, t.Text)
+		default:
+			log.Printf("warning: unable to parse \"%s\"", formatCTokens(decl))
+			return base, nil, false
+		}
+	}
+
+	switch {
+	case base.Base != "":
+	case len(keywords) > 0:
+		base.Base = canonicalCType(keywords)
+	case len(unknown) > 0:
+		// unknown identifiers are most likely export macros defined elsewhere, except the type name itself
+		base.Base = unknown[len(unknown)-1]
+	default:
+		return base, nil, false
+	}
+
+	return base, decl[i:], true
+}
+
+// canonicalCType returns the canonical name of a type made up of keywords, e.g. "unsigned long long".
+func canonicalCType(keywords []string) string {
+	count := map[string]int{}
+	for _, k := range keywords {
+		count[k]++
+	}
+
+	prefix := ""
+	if count["unsigned"] > 0 {
+		prefix = "unsigned "
+	}
+
+	switch {
+	case count["void"] > 0:
+		return "void"
+	case count["_Bool"] > 0 || count["bool"] > 0:
+		return "bool"
+	case count["char"] > 0:
+		if count["signed"] > 0 {
+			return "signed char"
+		}
+		return prefix + "char"
+	case count["short"] > 0:
+		return prefix + "short"
+	case count["long"] >= 2:
+		return prefix + "long long"
+	case count["double"] > 0 && count["long"] > 0:
+		return "long double"
+	case count["double"] > 0:
+		return "double"
+	case count["float"] > 0:
+		return "float"
+	case count["long"] == 1:
+		return prefix + "long"
+	default:
+		return prefix + "int"
+	}
+}
+
+// parseTagged parses "struct foo", "struct [foo] { ... }" and the like. It returns the canonical type name, and how
+// many tokens are consumed.
+func (h *cHeader) parseTagged(tokens []cToken) (name string, consumed int, ok bool) {
+	kind := tokens[0].Text
+	i := 1
+	tag := ""
+	if i < len(tokens) && tokens[i].Kind == cTokenIdent {
+		tag = tokens[i].Text
+		i++
+	}
+
+	if i >= len(tokens) || tokens[i].Text != "{" {
+		if tag == "" {
+			return "", 0, false
+		}
+		if kind == "struct" {
+			if _, ok := h.structs[kind+" "+tag]; !ok {
+				s := &cStruct{Name: tag, Tag: tag, Opaque: true, key: kind + " " + tag}
+				h.structs[kind+" "+tag] = s
+				h.Structs = append(h.Structs, s)
+			}
+		}
+		return kind + " " + tag, i, true
+	}
+
+	// find the matching brace
+	end := i
+	depth := 0
+	for ; end < len(tokens); end++ {
+		if tokens[end].Text == "{" {
+			depth++
+		} else if tokens[end].Text == "}" {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+	}
+	if end >= len(tokens) {
+		return "", 0, false
+	}
+	body := tokens[i+1 : end]
+
+	switch kind {
+	case "enum":
+		key := "enum " + tag
+		if tag == "" {
+			key = fmt.Sprintf("enum $%d", len(h.Enums))
+		}
+		e := &cEnum{Name: tag, Tag: tag}
+		h.parseEnum(e, body)
+		h.enums[key] = e
+		h.Enums = append(h.Enums, e)
+		return key, end + 1, true
+	case "union":
+		log.Printf("warning: union \"%s\" is not supported", tag)
+		return "union " + tag, end + 1, true
+	default:
+		key := "struct " + tag
+		if tag == "" {
+			key = fmt.Sprintf("struct $%d", len(h.Structs))
+		}
+		s, exists := h.structs[key]
+		if !exists {
+			s = &cStruct{Tag: tag, Name: tag, key: key}
+			h.structs[key] = s
+			h.Structs = append(h.Structs, s)
+		}
+		s.Opaque = false
+		s.Fields, ok = h.parseFields(tag, body)
+		if !ok {
+			s.Opaque = true
+			s.Fields = nil
+		}
+		return key, end + 1, true
+	}
+}
+
+// parseFields parses the body of a struct.
+func (h *cHeader) parseFields(tag string, body []cToken) (fields []cField, ok bool) {
+	for _, decl := range splitTopLevel(body, ";") {
+		decl = stripDecorationFunctions(decl)
+		if len(decl) == 0 {
+			continue
+		}
+
+		base, rest, ok := h.parseSpecifiers(decl)
+		if !ok {
+			return nil, false
+		}
+		if len(rest) == 0 {
+			log.Printf("warning: anonymous member in struct \"%s\" is not supported", tag)
+			return nil, false
+		}
+
+		for _, declarator := range splitTopLevel(rest, ",") {
+			for _, t := range declarator {
+				if t.Text == ":" {
+					log.Printf("warning: bit field in struct \"%s\" is not supported", tag)
+					return nil, false
+				}
+			}
+
+			name, t, _, _, ok := h.parseDeclarator(base, declarator)
+			if !ok || name == "" {
+				return nil, false
+			}
+			fields = append(fields, cField{Name: name, Type: t})
+		}
+	}
+
+	return fields, true
+}
+
+// parseEnum parses the body of an enum.
+func (h *cHeader) parseEnum(e *cEnum, body []cToken) {
+	next := int64(0)
+	for _, item := range splitTopLevel(body, ",") {
+		item = stripDecorationFunctions(item)
+		if len(item) == 0 || item[0].Kind != cTokenIdent {
+			continue
+		}
+
+		if len(item) > 2 && item[1].Text == "=" {
+			v, ok := h.evaluate(item[2:])
+			if !ok {
+				log.Printf("warning: unable to evaluate the value of \"%s\"", item[0].Text)
+				continue
+			}
+			next = v.value
+		}
+
+		e.Enumerators = append(e.Enumerators, cEnumerator{Name: item[0].Text, Value: next})
+		h.values[item[0].Text] = next
+		next++
+	}
+}
+
+// parseDeclarator parses a (possibly abstract) declarator like "*foo", "foo[3]", "(*cb)(int)" or "foo(int a)".
+func (h *cHeader) parseDeclarator(base cType, tokens []cToken) (name string, t cType, params []cParam, variadic bool, ok bool) {
+	t = base
+	i := 0
+
+	// pointers
+	for ; i < len(tokens); i++ {
+		switch {
+		case tokens[i].Text == "*":
+			t.Pointers++
+			continue
+		case tokens[i].Text == "const" || cDecorations[tokens[i].Text]:
+			continue
+		}
+		break
+	}
+
+	// (*name) or (name)
+	if i < len(tokens) && tokens[i].Text == "(" && i+1 < len(tokens) && tokens[i+1].Text != ")" && !h.isTypeStart(tokens[i+1]) {
+		end := matchingParen(tokens, i)
+		if end < 0 {
+			return "", t, nil, false, false
+		}
+		inner := tokens[i+1 : end]
+		// decorations inside, e.g. (WINAPI *name)
+		pointers := 0
+		for _, it := range inner {
+			if it.Text == "*" {
+				pointers++
+			} else if it.Kind == cTokenIdent && !cDecorations[it.Text] {
+				name = it.Text
+			}
+		}
+		i = end + 1
+		if i < len(tokens) && tokens[i].Text == "(" {
+			// pointer to function
+			t = cType{Base: "void", Pointers: pointers, Func: true}
+			return name, t, nil, false, true
+		}
+		t.Pointers += pointers
+	} else if i < len(tokens) && tokens[i].Kind == cTokenIdent {
+		name = tokens[i].Text
+		i++
+	}
+
+	for i < len(tokens) {
+		switch tokens[i].Text {
+		case "[":
+			end := i + 1
+			for end < len(tokens) && tokens[end].Text != "]" {
+				end++
+			}
+			if end >= len(tokens) {
+				return "", t, nil, false, false
+			}
+			dim := ""
+			if end > i+1 {
+				v, ok := h.evaluate(tokens[i+1 : end])
+				if !ok {
+					log.Printf("warning: unable to evaluate the size of array \"%s\"", name)
+					return "", t, nil, false, false
+				}
+				dim = strconv.FormatInt(v.value, 10)
+			}
+			t.Array = append(t.Array, dim)
+			i = end + 1
+		case "(":
+			end := matchingParen(tokens, i)
+			if end < 0 {
+				return "", t, nil, false, false
+			}
+			params, variadic, ok = h.parseParams(tokens[i+1 : end])
+			if !ok {
+				return "", t, nil, false, false
+			}
+			t.Func = true
+			i = end + 1
+		case "=":
+			// initializers are ignored
+			return name, t, params, variadic, true
+		default:
+			if cDecorations[tokens[i].Text] {
+				i++
+				continue
+			}
+			log.Printf("warning: unable to parse \"%s\"", formatCTokens(tokens))
+			return "", t, nil, false, false
+		}
+	}
+
+	if params == nil && t.Func {
+		params = []cParam{}
+	}
+	return name, t, params, variadic, true
+}
+
+// isTypeStart tests if t can start a type name, which tells a parameter list apart from a parenthesized declarator.
+func (h *cHeader) isTypeStart(t cToken) bool {
+	if cTypeKeywords[t.Text] || t.Text == "struct" || t.Text == "union" || t.Text == "enum" || t.Text == "const" {
+		return true
+	}
+	_, ok := h.typedefs[t.Text]
+	return ok || knownCTypedef(t.Text)
+}
+
+func matchingParen(tokens []cToken, start int) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		if tokens[i].Text == "(" {
+			depth++
+		} else if tokens[i].Text == ")" {
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseParams parses a function parameter list.
+func (h *cHeader) parseParams(tokens []cToken) (params []cParam, variadic bool, ok bool) {
+	params = []cParam{}
+	if len(tokens) == 0 || (len(tokens) == 1 && tokens[0].Text == "void") {
+		return params, false, true
+	}
+
+	for _, p := range splitTopLevel(tokens, ",") {
+		if len(p) == 1 && p[0].Text == "..." {
+			variadic = true
+			continue
+		}
+
+		base, rest, ok := h.parseSpecifiers(p)
+		if !ok {
+			return nil, false, false
+		}
+		name, t, _, _, ok := h.parseDeclarator(base, rest)
+		if !ok {
+			return nil, false, false
+		}
+		// arrays decay into pointers
+		if len(t.Array) > 0 {
+			t.Array = t.Array[1:]
+			t.Pointers++
+		}
+		params = append(params, cParam{Name: name, Type: t})
+	}
+
+	return params, variadic, true
+}
+
+func formatCTokens(tokens []cToken) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 {
+			prev := tokens[i-1].Text
+			if t.Text != "," && t.Text != ")" && t.Text != "]" && t.Text != ";" && t.Text != "[" &&
+				prev != "(" && prev != "[" && !(t.Text == "(" && tokens[i-1].Kind == cTokenIdent) &&
+				!(prev == "*" && (t.Kind == cTokenIdent || t.Text == "*")) {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(t.Text)
+	}
+	return b.String()
+}
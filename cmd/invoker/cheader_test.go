@@ -0,0 +1,139 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testHeader = `
+#ifndef FOO_H
+#define FOO_H
+
+#ifdef _WIN32
+#  define FOO_API __declspec(dllimport) __stdcall
+#else
+#  define FOO_API __attribute__((visibility("default")))
+#endif
+#define FOO_EXPORT(name) FOO_API name
+
+#define FOO_VERSION "1.0"
+#define FOO_MAX_NAME (1 << 5)
+#define FOO_PI 3.14f
+#define FOO_MASK 0xff00U
+
+/* an opaque handle */
+typedef struct foo_context foo_context;
+
+typedef enum {
+	FOO_OK = 0,
+	FOO_ERROR = -1,
+	FOO_AGAIN = FOO_ERROR - 1,
+} foo_status;
+
+typedef struct foo_point_s {
+	int x, y;
+	char name[FOO_MAX_NAME];
+	const char *label;
+	void (*callback)(void *);
+} foo_point;
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+foo_status FOO_EXPORT(foo_open)(const char *path, foo_context **ctx);
+void FOO_API foo_close(foo_context *ctx);
+long FOO_API foo_length(const foo_point *p, unsigned int flags);
+int FOO_API foo_printf(foo_context *ctx, const char *format, ...);
+int FOO_API foo_by_value(foo_point p);
+int FOO_API foo_unexported(void);
+
+static inline int foo_inline(int a) { return a + 1; }
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
+`
+
+func TestParseCHeader(t *testing.T) {
+	h := parseCHeader(testHeader, "linux")
+
+	var names []string
+	for _, f := range h.Functions {
+		names = append(names, f.Name)
+	}
+	assert.EqualValues(t, []string{"foo_open", "foo_close", "foo_length", "foo_printf", "foo_by_value", "foo_unexported"}, names)
+
+	f := h.Functions[0]
+	assert.EqualValues(t, "foo_status", f.Return.Base)
+	assert.EqualValues(t, []cParam{
+		{Name: "path", Type: cType{Base: "char", Const: true, Pointers: 1}},
+		{Name: "ctx", Type: cType{Base: "foo_context", Pointers: 2}},
+	}, f.Params)
+	assert.EqualValues(t, "foo_status foo_open(const char *path, foo_context **ctx);", f.Prototype)
+	assert.True(t, h.Functions[3].Variadic)
+
+	assert.EqualValues(t, -2, h.values["FOO_AGAIN"])
+
+	s := h.structs["struct foo_point_s"]
+	if assert.NotNil(t, s) {
+		assert.EqualValues(t, "foo_point", s.Name)
+		assert.Len(t, s.Fields, 5)
+		assert.EqualValues(t, []string{"32"}, s.Fields[2].Type.Array)
+	}
+	assert.True(t, h.structs["struct foo_context"].Opaque)
+}
+
+func TestBindHeader(t *testing.T) {
+	fields := map[string]string{
+		"foo_open":     "FooOpen",
+		"foo_close":    "FooClose",
+		"foo_length":   "FooLength",
+		"foo_printf":   "FooPrintf",
+		"foo_by_value": "FooByValue",
+	}
+
+	for goos, long := range map[string]string{"linux": "int", "windows": "int32"} {
+		h := parseCHeader(testHeader, goos)
+		constants, types, wrappers, imports := bindHeader(h, goos, &namer{}, map[string]bool{}, fields)
+
+		values := map[string]string{}
+		for _, c := range constants {
+			values[c.Name] = c.Value
+		}
+		assert.EqualValues(t, `"1.0"`, values["FOOVERSION"])
+		assert.EqualValues(t, "32", values["FOOMAXNAME"])
+		assert.EqualValues(t, "3.14", values["FOOPI"])
+		assert.EqualValues(t, "0xff00", values["FOOMASK"])
+		assert.EqualValues(t, "-2", values["FOOAGAIN"])
+
+		definitions := map[string]string{}
+		for _, d := range types {
+			definitions[d.Name] = d.Definition
+		}
+		assert.EqualValues(t, "struct{}", definitions["Foocontext"])
+		assert.EqualValues(t, "int32", definitions["Foostatus"])
+		assert.Contains(t, definitions["Foopoint"], "Name [32]int8")
+		assert.Contains(t, definitions["Foopoint"], "Label *int8")
+		assert.Contains(t, definitions["Foopoint"], "Callback uintptr")
+
+		// variadic functions, structs passed by value and functions not exported are skipped
+		assert.Len(t, wrappers, 3)
+		assert.EqualValues(t, wrapper{
+			Method:    "CallFooOpen",
+			Field:     "FooOpen",
+			Function:  "foo_open",
+			Params:    "path string, ctx **Foocontext",
+			Args:      []string{"path", "ctx"},
+			Result:    "Foostatus",
+			Prototype: "foo_status foo_open(const char *path, foo_context **ctx);",
+		}, wrappers[0])
+		assert.EqualValues(t, "", wrappers[1].Result)
+		assert.EqualValues(t, "p *Foopoint, flags uint32", wrappers[2].Params)
+		assert.EqualValues(t, long, wrappers[2].Result)
+
+		assert.Empty(t, imports)
+	}
+}
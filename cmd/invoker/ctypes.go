@@ -0,0 +1,405 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// cScalarTypes maps C types to Go types, where the size does not depend on the platform.
+var cScalarTypes = map[string]string{
+	"char": "int8", "signed char": "int8", "unsigned char": "uint8",
+	"short": "int16", "unsigned short": "uint16",
+	"int": "int32", "unsigned int": "uint32",
+	"long long": "int64", "unsigned long long": "uint64",
+	"float": "float32", "double": "float64", "bool": "bool",
+
+	// <stdint.h>, <stddef.h> and friends
+	"int8_t": "int8", "int16_t": "int16", "int32_t": "int32", "int64_t": "int64",
+	"uint8_t": "uint8", "uint16_t": "uint16", "uint32_t": "uint32", "uint64_t": "uint64",
+	"size_t": "uintptr", "ssize_t": "int", "ptrdiff_t": "int", "intptr_t": "int", "uintptr_t": "uintptr",
+	"off_t": "int64", "off64_t": "int64",
+
+	// <windows.h>
+	"BOOL": "int32", "BOOLEAN": "uint8", "BYTE": "uint8", "UCHAR": "uint8", "CHAR": "int8",
+	"WORD": "uint16", "USHORT": "uint16", "SHORT": "int16", "WCHAR": "uint16",
+	"DWORD": "uint32", "UINT": "uint32", "ULONG": "uint32", "INT": "int32", "LONG": "int32",
+	"HRESULT": "int32", "NTSTATUS": "int32", "FLOAT": "float32",
+	"DWORD64": "uint64", "QWORD": "uint64", "ULONG64": "uint64", "LONGLONG": "int64", "ULONGLONG": "uint64",
+	"INT_PTR": "uintptr", "UINT_PTR": "uintptr", "LONG_PTR": "uintptr", "ULONG_PTR": "uintptr",
+	"DWORD_PTR": "uintptr", "SIZE_T": "uintptr", "SSIZE_T": "uintptr",
+	"WPARAM": "uintptr", "LPARAM": "uintptr", "LRESULT": "uintptr", "ATOM": "uint16",
+}
+
+// cPointerTypedefs are well-known typedefs of pointer types.
+var cPointerTypedefs = map[string]cType{
+	"LPSTR": {Base: "char", Pointers: 1}, "LPCSTR": {Base: "char", Const: true, Pointers: 1},
+	"PSTR": {Base: "char", Pointers: 1}, "PCSTR": {Base: "char", Const: true, Pointers: 1},
+	"LPWSTR": {Base: "WCHAR", Pointers: 1}, "LPCWSTR": {Base: "WCHAR", Const: true, Pointers: 1},
+	"PWSTR": {Base: "WCHAR", Pointers: 1}, "PCWSTR": {Base: "WCHAR", Const: true, Pointers: 1},
+	"LPVOID": {Base: "void", Pointers: 1}, "PVOID": {Base: "void", Pointers: 1},
+	"LPCVOID": {Base: "void", Const: true, Pointers: 1},
+	"LPDWORD": {Base: "DWORD", Pointers: 1}, "PDWORD": {Base: "DWORD", Pointers: 1},
+	"LPBYTE": {Base: "BYTE", Pointers: 1}, "PBYTE": {Base: "BYTE", Pointers: 1},
+	"LPBOOL": {Base: "BOOL", Pointers: 1}, "PBOOL": {Base: "BOOL", Pointers: 1},
+	"LPHANDLE": {Base: "HANDLE", Pointers: 1}, "PHANDLE": {Base: "HANDLE", Pointers: 1},
+}
+
+// handles, e.g. HANDLE, HWND or HMODULE
+var cHandleType = regexp.MustCompile(`^H[A-Z0-9_]+$`)
+
+// knownCTypedef tests if name is a type well-known without being declared.
+func knownCTypedef(name string) bool {
+	_, scalar := cScalarTypes[name]
+	_, pointer := cPointerTypedefs[name]
+	return scalar || pointer || name == "long" || name == "wchar_t" || cHandleType.MatchString(name)
+}
+
+var errorUnsupportedType = errors.New("unsupported type")
+
+// cTypeMapper maps C types to Go types for a specific platform.
+type cTypeMapper struct {
+	h          *cHeader
+	windows    bool            // LLP64, as opposed to LP64
+	failed     map[string]bool // typedefs and structs without a Go counterpart
+	usesUnsafe bool
+	names      *namer
+}
+
+// resolve follows typedefs until the base type is not a typedef.
+func (m *cTypeMapper) resolve(t cType) cType {
+	for i := 0; i < 32 && !t.Func; i++ {
+		next, ok := m.h.typedefs[t.Base]
+		if !ok {
+			next, ok = cPointerTypedefs[t.Base]
+		}
+		if !ok {
+			break
+		}
+
+		next.Pointers += t.Pointers
+		next.Array = append(append([]string{}, t.Array...), next.Array...)
+		next.Const = next.Const || (t.Const && next.Pointers == t.Pointers)
+		t = next
+	}
+	return t
+}
+
+// goBaseType maps a C base type to a Go type name.
+func (m *cTypeMapper) goBaseType(base string) (string, error) {
+	switch {
+	case base == "long" && m.windows:
+		return "int32", nil
+	case base == "long":
+		return "int", nil
+	case base == "unsigned long" && m.windows:
+		return "uint32", nil
+	case base == "unsigned long":
+		return "uint", nil
+	case base == "wchar_t" && m.windows:
+		return "uint16", nil
+	case base == "wchar_t":
+		return "int32", nil
+	case m.failed[base]:
+		return "", fmt.Errorf("%w \"%s\"", errorUnsupportedType, base)
+	}
+
+	if s, ok := m.h.structs[base]; ok {
+		return m.names.name(s.Name), nil
+	}
+	if e, ok := m.h.enums[base]; ok {
+		if e.Name == "" {
+			return "int32", nil
+		}
+		return m.names.name(e.Name), nil
+	}
+	if _, ok := m.h.typedefs[base]; ok {
+		return m.names.name(base), nil
+	}
+	if goType, ok := cScalarTypes[base]; ok {
+		return goType, nil
+	}
+	if cHandleType.MatchString(base) {
+		return "uintptr", nil
+	}
+
+	return "", fmt.Errorf("%w \"%s\"", errorUnsupportedType, base)
+}
+
+// goType maps a C type to a Go type. Parameters and return values (field == false) map "const char *" to string,
+// and do not support passing structs by value. void maps to "".
+func (m *cTypeMapper) goType(t cType, field bool) (string, error) {
+	if t.Func {
+		return "uintptr", nil
+	}
+
+	resolved := m.resolve(t)
+	if resolved.Func && resolved.Pointers > 0 && resolved.Pointers == t.Pointers {
+		// pointer to a function type
+		return "uintptr", nil
+	}
+
+	// typedefs without a Go counterpart are replaced by what they stand for
+	_, declared := m.h.typedefs[t.Base]
+	_, wellKnownPointer := cPointerTypedefs[t.Base]
+	if (declared && m.failed[t.Base]) || (!declared && wellKnownPointer) {
+		t = resolved
+	}
+
+	if !field && len(t.Array) == 0 {
+		if resolved.Base == "char" && resolved.Const && resolved.Pointers == 1 {
+			return "string", nil
+		}
+		if resolved.Pointers == 0 && (strings.HasPrefix(resolved.Base, "struct ") || strings.HasPrefix(resolved.Base, "union ")) {
+			return "", fmt.Errorf("%w: passing \"%s\" by value", errorUnsupportedType, resolved.Base)
+		}
+	}
+
+	prefix := ""
+	for _, dim := range t.Array {
+		if dim == "" {
+			return "", fmt.Errorf("%w: flexible array", errorUnsupportedType)
+		}
+		prefix += "[" + dim + "]"
+	}
+
+	if t.Base == "void" {
+		if t.Pointers == 0 {
+			return "", nil
+		}
+		m.usesUnsafe = true
+		return prefix + strings.Repeat("*", t.Pointers-1) + "unsafe.Pointer", nil
+	}
+
+	base, err := m.goBaseType(t.Base)
+	if err != nil {
+		if t.Pointers > 0 {
+			// pointers to unknown types are opaque
+			m.usesUnsafe = true
+			return prefix + strings.Repeat("*", t.Pointers-1) + "unsafe.Pointer", nil
+		}
+		return "", err
+	}
+
+	return prefix + strings.Repeat("*", t.Pointers) + base, nil
+}
+
+// typeDefinitions generates the Go declarations for typedefs and structs, and marks those which cannot be
+// generated as failed.
+func (m *cTypeMapper) typeDefinitions() (types []typeDefinition, warnings []string) {
+	for _, t := range m.h.Typedefs {
+		if m.failed[t.Name] {
+			continue
+		}
+		goType, err := m.goType(t.Type, true)
+		if err == nil && goType == "" {
+			// typedefs of void, which are only ever used as opaque pointers
+			m.failed[t.Name] = true
+			continue
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("typedef \"%s\": %v", t.Name, err))
+			m.failed[t.Name] = true
+			continue
+		}
+		types = append(types, typeDefinition{Name: m.names.name(t.Name), Definition: "= " + goType, CName: t.Name})
+	}
+
+	for _, s := range m.h.Structs {
+		key := s.key
+		if m.failed[key] {
+			continue
+		}
+		if strings.HasPrefix(s.Name, "$") || s.Name == "" {
+			// anonymous structs which are not typedef'd are never referred to
+			m.failed[key] = true
+			continue
+		}
+
+		if s.Opaque {
+			types = append(types, typeDefinition{Name: m.names.name(s.Name), Definition: "struct{}", CName: s.Name})
+			continue
+		}
+
+		var b strings.Builder
+		b.WriteString("struct {\n")
+		used := map[string]bool{}
+		for _, f := range s.Fields {
+			goType, err := m.goType(f.Type, true)
+			if err == nil && goType == "" {
+				err = errorUnsupportedType
+			}
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("struct \"%s\": field \"%s\": %v", s.Name, f.Name, err))
+				m.failed[key] = true
+				break
+			}
+			name := m.names.identifier(f.Name)
+			if name == "" {
+				name = "X"
+			}
+			for used[name] {
+				name += "_"
+			}
+			used[name] = true
+			fmt.Fprintf(&b, "%s %s // %s\n", name, goType, f.Name)
+		}
+		b.WriteString("}")
+		if !m.failed[key] {
+			types = append(types, typeDefinition{Name: m.names.name(s.Name), Definition: b.String(), CName: s.Name})
+		}
+	}
+
+	return
+}
+
+// dedupe removes declarations whose Go name is already used, with a warning.
+func dedupe[T any](declarations []T, name func(T) (goName, cName string), used map[string]bool) (ret []T) {
+	for _, d := range declarations {
+		goName, cName := name(d)
+		if used[goName] {
+			log.Printf("warning: \"%s\" is skipped, since \"%s\" is already declared", cName, goName)
+			continue
+		}
+		used[goName] = true
+		ret = append(ret, d)
+	}
+	return
+}
+
+// goParamName returns a valid Go identifier for a C parameter.
+func goParamName(name string, index int, used map[string]bool) string {
+	if name == "" {
+		name = fmt.Sprintf("a%d", index)
+	}
+	// the names used by the wrappers themselves
+	switch name {
+	case "dll", "err", "ok", "result", "errno", "syscall":
+		name += "_"
+	}
+	if token.IsKeyword(name) {
+		name += "_"
+	}
+	for used[name] {
+		name += "_"
+	}
+	used[name] = true
+	return name
+}
+
+// bindHeader generates the Go declarations for everything understood from h, named by names. fields maps the generated
+// exports to struct field names; prototypes of functions that are not exported at all are reported, and no wrapper is generated.
+func bindHeader(h *cHeader, goos string, names *namer, exported map[string]bool, fields map[string]string) (constants []constant, types []typeDefinition, wrappers []wrapper, imports []string) {
+	m := &cTypeMapper{h: h, windows: goos == "windows", failed: map[string]bool{}, names: names}
+
+	// a failed type might break others referring to it, so repeat until nothing changes
+	var warnings []string
+	for {
+		failed := len(m.failed)
+		m.usesUnsafe = false
+		var w []string
+		types, w = m.typeDefinitions()
+		warnings = append(warnings, w...)
+		if len(m.failed) == failed {
+			break
+		}
+	}
+	for _, w := range warnings {
+		log.Printf("warning: %s", w)
+	}
+
+	// enums
+	for _, e := range h.Enums {
+		typeName := ""
+		if e.Name != "" {
+			typeName = m.names.name(e.Name)
+			types = append(types, typeDefinition{Name: typeName, Definition: "int32", CName: e.Name})
+		}
+		for _, v := range e.Enumerators {
+			constants = append(constants, constant{Name: m.names.name(v.Name), Type: typeName, Value: fmt.Sprint(v.Value), CName: v.Name})
+		}
+	}
+
+	// macros
+	for _, macro := range h.Macros {
+		if h.macros[macro.Name] != macro {
+			// redefined or undefined later
+			continue
+		}
+		value, ok := h.macroConstant(macro.Body)
+		if !ok {
+			continue
+		}
+		constants = append(constants, constant{Name: m.names.name(macro.Name), Value: value, CName: macro.Name})
+	}
+
+	// functions
+	seen := map[string]bool{}
+	for _, f := range h.Functions {
+		if seen[f.Name] {
+			continue
+		}
+		seen[f.Name] = true
+
+		field, ok := fields[f.Name]
+		if !ok {
+			if !exported[f.Name] {
+				log.Printf("warning: function \"%s\" is declared in the header, but not exported", f.Name)
+			}
+			continue
+		}
+		if f.Variadic {
+			log.Printf("warning: function \"%s\": variadic functions are not supported", f.Name)
+			continue
+		}
+
+		w := wrapper{Method: "Call" + field, Field: field, Function: f.Name, Prototype: f.Prototype}
+		var err error
+		w.Result, err = m.goType(f.Return, false)
+		if err != nil {
+			log.Printf("warning: function \"%s\": return value: %v", f.Name, err)
+			continue
+		}
+
+		var params []string
+		used := map[string]bool{}
+		for i, p := range f.Params {
+			goType, err := m.goType(p.Type, false)
+			if err == nil && goType == "" {
+				err = errorUnsupportedType
+			}
+			if err != nil {
+				log.Printf("warning: function \"%s\": parameter \"%s\": %v", f.Name, p.Name, err)
+				ok = false
+				break
+			}
+			name := goParamName(p.Name, i, used)
+			params = append(params, name+" "+goType)
+			w.Args = append(w.Args, name)
+		}
+		if !ok {
+			continue
+		}
+		w.Params = strings.Join(params, ", ")
+		wrappers = append(wrappers, w)
+	}
+
+	sort.SliceStable(constants, func(i, j int) bool {
+		return constants[i].Type < constants[j].Type
+	})
+
+	// different C names might end up with the same Go name
+	used := map[string]bool{}
+	types = dedupe(types, func(t typeDefinition) (string, string) { return t.Name, t.CName }, used)
+	constants = dedupe(constants, func(c constant) (string, string) { return c.Name, c.CName }, used)
+	wrappers = dedupe(wrappers, func(w wrapper) (string, string) { return w.Method, w.Function }, map[string]bool{})
+
+	if m.usesUnsafe {
+		imports = append(imports, "unsafe")
+	}
+	return
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+)
+
+// demangledName is a C++ symbol decoded from its mangled name.
+type demangledName struct {
+	Scope     []string // enclosing namespaces and classes, outermost first
+	Name      string   // e.g. "Foo", "~Bar" or "operator+"
+	Signature string   // e.g. "void Bar::Foo(int)"
+	Data      bool     // a variable rather than a function
+}
+
+// cppOperatorWords names C++ operators in field names.
+var cppOperatorWords = map[string]string{
+	"new": "New", "new[]": "NewArray", "delete": "Delete", "delete[]": "DeleteArray", "+": "Add", "-": "Sub",
+	"*": "Mul", "/": "Div", "%": "Mod", "&": "And", "|": "Or", "^": "Xor", "~": "Compl", "!": "Not", "=": "Assign",
+	"+=": "AddAssign", "-=": "SubAssign", "*=": "MulAssign", "/=": "DivAssign", "%=": "ModAssign",
+	"&=": "AndAssign", "|=": "OrAssign", "^=": "XorAssign", "<<": "Shl", ">>": "Shr", "<<=": "ShlAssign",
+	">>=": "ShrAssign", "==": "Equal", "!=": "NotEqual", "<": "Less", ">": "Greater", "<=": "LessEqual",
+	">=": "GreaterEqual", "<=>": "Compare", "&&": "LogicalAnd", "||": "LogicalOr", "++": "Inc", "--": "Dec",
+	",": "Comma", "->*": "ArrowStar", "->": "Arrow", "()": "Call", "[]": "Index", "?": "Cond",
+}
+
+// demangle decodes an MSVC or Itanium C++ mangled name.
+func demangle(name string) (*demangledName, bool) {
+	if strings.HasPrefix(name, "?") {
+		return demangleMSVC(name)
+	}
+	return demangleItanium(name)
+}
+
+// FieldName returns a Go identifier made of the parts of the qualified name, e.g. "BarFoo" for "Bar::Foo",
+// "BarCtor" for the constructor of Bar or "BarOpAdd" for "Bar::operator+". Each part is formatted by format.
+func (n *demangledName) FieldName(format func(string) string) string {
+	var ret strings.Builder
+	parts := append(append([]string{}, n.Scope...), n.Name)
+	for i, part := range parts {
+		if j := strings.IndexByte(part, '<'); j > 0 && !strings.HasPrefix(part, "operator") {
+			// template arguments are only in the signature
+			part = part[:j]
+		}
+
+		switch {
+		case strings.HasPrefix(part, "(anonymous") || strings.HasPrefix(part, "`anonymous"):
+			continue
+		case i == len(parts)-1 && isConstructorOrDestructor(parts):
+			if strings.HasPrefix(part, "~") {
+				part = "Dtor"
+			} else {
+				part = "Ctor"
+			}
+		case strings.HasPrefix(part, "operator"):
+			op := strings.TrimSpace(strings.TrimPrefix(part, "operator"))
+			if word, ok := cppOperatorWords[op]; ok {
+				part = "Op" + word
+			} else {
+				// conversions
+				part = "Op" + op
+			}
+		}
+
+		if strings.Trim(part, "_`'") == "" || nonAlphanumeric(part) {
+			continue
+		}
+		ret.WriteString(format(part))
+	}
+
+	return ret.String()
+}
+
+// nonAlphanumeric tests if s has no letters or digits.
+func nonAlphanumeric(s string) bool {
+	for _, c := range s {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitScope splits a qualified name at "::", but not inside template arguments or parentheses.
+func splitScope(name string) []string {
+	var parts []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '<', '(':
+			depth++
+		case '>', ')':
+			depth--
+		case ':':
+			if depth == 0 && strings.HasPrefix(name[i:], "::") && !strings.HasSuffix(name[:i], "operator") {
+				parts = append(parts, name[start:i])
+				start = i + 2
+				i++
+			}
+		}
+	}
+	return append(parts, name[start:])
+}
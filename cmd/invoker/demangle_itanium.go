@@ -0,0 +1,852 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// itaniumBuiltinTypes are the one-letter builtin types of the Itanium C++ ABI.
+var itaniumBuiltinTypes = map[byte]string{
+	'v': "void", 'w': "wchar_t", 'b': "bool", 'c': "char", 'a': "signed char", 'h': "unsigned char",
+	's': "short", 't': "unsigned short", 'i': "int", 'j': "unsigned int", 'l': "long", 'm': "unsigned long",
+	'x': "long long", 'y': "unsigned long long", 'n': "__int128", 'o': "unsigned __int128",
+	'f': "float", 'd': "double", 'e': "long double", 'g': "__float128", 'z': "...",
+}
+
+// itaniumDBuiltinTypes are the builtin types starting with "D".
+var itaniumDBuiltinTypes = map[byte]string{
+	'd': "decimal64", 'e': "decimal128", 'f': "decimal32", 'h': "half", 'i': "char32_t", 's': "char16_t",
+	'u': "char8_t", 'a': "auto", 'c': "decltype(auto)", 'n': "decltype(nullptr)",
+}
+
+// itaniumStdSubstitutions are the predefined substitutions.
+var itaniumStdSubstitutions = map[byte]string{
+	'a': "std::allocator",
+	'b': "std::basic_string",
+	's': "std::basic_string<char, std::char_traits<char>, std::allocator<char> >",
+	'i': "std::basic_istream<char, std::char_traits<char> >",
+	'o': "std::basic_ostream<char, std::char_traits<char> >",
+	'd': "std::basic_iostream<char, std::char_traits<char> >",
+}
+
+// itaniumOperators maps operator codes to their C++ tokens.
+var itaniumOperators = map[string]string{
+	"nw": "new", "na": "new[]", "dl": "delete", "da": "delete[]", "ps": "+", "ng": "-", "ad": "&", "de": "*",
+	"co": "~", "pl": "+", "mi": "-", "ml": "*", "dv": "/", "rm": "%", "an": "&", "or": "|", "eo": "^", "aS": "=",
+	"pL": "+=", "mI": "-=", "mL": "*=", "dV": "/=", "rM": "%=", "aN": "&=", "oR": "|=", "eO": "^=", "ls": "<<",
+	"rs": ">>", "lS": "<<=", "rS": ">>=", "eq": "==", "ne": "!=", "lt": "<", "gt": ">", "le": "<=", "ge": ">=",
+	"ss": "<=>", "nt": "!", "aa": "&&", "oo": "||", "pp": "++", "mm": "--", "cm": ",", "pm": "->*", "pt": "->",
+	"cl": "()", "ix": "[]", "qu": "?",
+}
+
+// itaniumDemangler decodes a name mangled with the Itanium C++ ABI, which is used by GCC and Clang everywhere but on
+// Windows. The output looks like c++filt's. Anything uncommon in exported symbols (expressions, lambdas in default
+// arguments, vector types...) fails the whole name.
+type itaniumDemangler struct {
+	s      string
+	pos    int
+	failed bool
+
+	subs          []string            // substitution candidates, in order of appearance
+	templateArgs  []string            // of the function, referred to by template parameters
+	packs         map[string][]string // elements of argument packs in templateArgs
+	expanding     bool                // a pack expansion, of the packIndex-th element
+	packIndex     int
+	packLength    int
+	functionTypes map[string]bool // so pointers to them are formatted as such
+	arrayTypes    map[string]bool
+	declarators   map[string]int // pointers to functions or arrays, and where to insert further pointers
+	depth         int            // of nested types
+	last          string         // the last source name, for constructors and destructors
+}
+
+// demangleItanium demangles name, if it is an Itanium C++ mangled name.
+func demangleItanium(name string) (*demangledName, bool) {
+	if !strings.HasPrefix(name, "_Z") {
+		return nil, false
+	}
+
+	d := &itaniumDemangler{s: name, pos: 2, functionTypes: map[string]bool{}, arrayTypes: map[string]bool{}, declarators: map[string]int{}, packs: map[string][]string{}}
+	ret := d.encoding()
+
+	// vendor suffixes like ".cold" or ".isra.0" are ignored
+	if d.failed || (d.pos < len(d.s) && d.s[d.pos] != '.') {
+		return nil, false
+	}
+	return ret, true
+}
+
+func (d *itaniumDemangler) fail() {
+	d.failed = true
+	d.pos = len(d.s)
+}
+
+func (d *itaniumDemangler) peek() byte {
+	if d.pos < len(d.s) {
+		return d.s[d.pos]
+	}
+	return 0
+}
+
+func (d *itaniumDemangler) peekAt(offset int) byte {
+	if d.pos+offset < len(d.s) {
+		return d.s[d.pos+offset]
+	}
+	return 0
+}
+
+func (d *itaniumDemangler) consume(prefix string) bool {
+	if strings.HasPrefix(d.s[d.pos:], prefix) {
+		d.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+// atEnd tests if there is nothing left in the current encoding.
+func (d *itaniumDemangler) atEnd() bool {
+	return d.pos >= len(d.s) || d.s[d.pos] == '.' || d.s[d.pos] == 'E'
+}
+
+func (d *itaniumDemangler) encoding() *demangledName {
+	// special names
+	for prefix, description := range map[string]string{"TV": "vtable", "TT": "VTT", "TI": "typeinfo", "TS": "typeinfo name"} {
+		if d.consume(prefix) {
+			t := d.typ()
+			return &demangledName{
+				Scope:     splitScope(t),
+				Name:      description,
+				Signature: description + " for " + t,
+				Data:      true,
+			}
+		}
+	}
+	if d.consume("GV") {
+		name, _, _ := d.name()
+		return &demangledName{
+			Scope:     splitScope(name),
+			Name:      "guard variable",
+			Signature: "guard variable for " + name,
+			Data:      true,
+		}
+	}
+	if description := d.thunk(); description != "" {
+		ret := d.encoding()
+		if ret != nil {
+			ret.Signature = description + ret.Signature
+		}
+		return ret
+	}
+	if d.peek() == 'T' || d.peek() == 'G' {
+		// other compiler-generated symbols
+		d.fail()
+		return nil
+	}
+
+	name, template, cv := d.name()
+	parts := splitScope(name)
+	ret := &demangledName{
+		Scope: parts[:len(parts)-1],
+		Name:  parts[len(parts)-1],
+	}
+	if d.atEnd() {
+		// a variable
+		ret.Signature = name
+		ret.Data = true
+		return ret
+	}
+
+	var result string
+	if template && !isConstructorOrDestructor(parts) && !strings.HasPrefix(ret.Name, "operator ") {
+		result = d.typ() + " "
+	}
+
+	params := d.params()
+	ret.Signature = result + name + "(" + strings.Join(params, ", ") + ")" + cv
+	return ret
+}
+
+// thunk parses the prefix of a thunk or a transaction clone, and describes it.
+func (d *itaniumDemangler) thunk() string {
+	switch {
+	case d.consume("Th"):
+		d.callOffset()
+		return "non-virtual thunk to "
+	case d.consume("Tv"):
+		d.callOffset()
+		d.callOffset()
+		return "virtual thunk to "
+	case d.consume("Tc"):
+		for i := 0; i < 2; i++ {
+			if d.consume("h") {
+				d.callOffset()
+			} else if d.consume("v") {
+				d.callOffset()
+				d.callOffset()
+			} else {
+				d.fail()
+			}
+		}
+		return "covariant return thunk to "
+	case d.consume("GTt"), d.consume("GTn"):
+		return "transaction clone for "
+	default:
+		return ""
+	}
+}
+
+// callOffset skips an offset of a thunk.
+func (d *itaniumDemangler) callOffset() {
+	d.consume("n")
+	d.number()
+	if !d.consume("_") {
+		d.fail()
+	}
+}
+
+// params parses the parameter types of a function.
+func (d *itaniumDemangler) params() []string {
+	var params []string
+	for !d.failed && !d.atEnd() {
+		// might be an empty pack
+		if t := d.typ(); t != "" {
+			params = append(params, t)
+		}
+	}
+	if len(params) == 1 && params[0] == "void" {
+		params = nil
+	}
+	return params
+}
+
+// name parses a <name>, and tells if it is a template, whose return type is encoded. If it is a member function, its
+// cv-qualifiers are returned as well.
+func (d *itaniumDemangler) name() (name string, template bool, cv string) {
+	switch {
+	case d.peek() == 'N':
+		return d.nestedName()
+	case d.peek() == 'Z':
+		// local names are never exported
+		d.fail()
+		return "", false, ""
+	case d.consume("St"):
+		name = "std::" + d.unqualifiedName()
+	case d.peek() == 'S':
+		// a substitution must be followed by template arguments here
+		name = d.substitution()
+		if d.peek() != 'I' {
+			d.fail()
+			return "", false, ""
+		}
+		return d.withTemplateArgs(name), true, ""
+	default:
+		name = d.unqualifiedName()
+	}
+
+	if d.peek() == 'I' {
+		d.subs = append(d.subs, name)
+		return d.withTemplateArgs(name), true, ""
+	}
+	return name, false, ""
+}
+
+func (d *itaniumDemangler) nestedName() (name string, template bool, cv string) {
+	d.consume("N")
+
+	var qualifiers []string
+	if d.consume("r") {
+		qualifiers = append(qualifiers, "restrict")
+	}
+	if d.consume("V") {
+		qualifiers = append(qualifiers, "volatile")
+	}
+	if d.consume("K") {
+		qualifiers = append(qualifiers, "const")
+	}
+	for i, j := 0, len(qualifiers)-1; i < j; i, j = i+1, j-1 {
+		qualifiers[i], qualifiers[j] = qualifiers[j], qualifiers[i]
+	}
+	if len(qualifiers) > 0 {
+		cv = " " + strings.Join(qualifiers, " ")
+	}
+	if d.consume("R") {
+		cv += " &"
+	} else if d.consume("O") {
+		cv += " &&"
+	}
+
+	for !d.failed && !d.consume("E") {
+		template = false
+		c := d.peek()
+		switch {
+		case c == 'S' && d.peekAt(1) == 't':
+			d.pos += 2
+			name = "std"
+			continue
+		case c == 'S':
+			if name != "" {
+				d.fail()
+				break
+			}
+			name = d.substitution()
+			d.last = lastName(name)
+		case c == 'I':
+			if name == "" {
+				d.fail()
+				break
+			}
+			name = d.withTemplateArgs(name)
+			template = true
+		case c == 'T':
+			name = joinScope(name, d.templateParam())
+		case c == 'D' && (d.peekAt(1) == 't' || d.peekAt(1) == 'T'):
+			// decltype
+			d.fail()
+		case c == 'M':
+			// the context of a lambda in an initializer
+			d.pos++
+			continue
+		default:
+			name = joinScope(name, d.unqualifiedName())
+		}
+
+		// every prefix is a substitution candidate, but the whole name is only one if it is a type; substitutions
+		// are candidates already
+		if d.peek() != 'E' && c != 'S' {
+			d.subs = append(d.subs, name)
+		}
+	}
+
+	return name, template, cv
+}
+
+// joinScope qualifies name with scope.
+func joinScope(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "::" + name
+}
+
+func (d *itaniumDemangler) unqualifiedName() string {
+	var name string
+	switch c := d.peek(); {
+	case c >= '0' && c <= '9':
+		name = d.sourceName()
+		d.last = name
+	case c == 'L':
+		// internal linkage
+		d.pos++
+		name = d.sourceName()
+		d.last = name
+	case c == 'C':
+		d.pos++
+		if d.consume("I") {
+			// inheriting constructor
+			d.pos++
+			d.typ()
+		} else if c := d.peek(); c >= '1' && c <= '5' {
+			d.pos++
+		} else {
+			d.fail()
+		}
+		name = d.last
+	case c == 'D':
+		d.pos++
+		if c := d.peek(); c >= '0' && c <= '5' {
+			d.pos++
+		} else {
+			d.fail()
+		}
+		name = "~" + d.last
+	case c == 'U':
+		name = d.unnamedType()
+	case c >= 'a' && c <= 'z':
+		name = d.operatorName()
+	default:
+		d.fail()
+	}
+
+	// ABI tags
+	for !d.failed && d.consume("B") {
+		name += "[abi:" + d.sourceName() + "]"
+	}
+
+	return name
+}
+
+func (d *itaniumDemangler) number() int {
+	start := d.pos
+	for d.pos < len(d.s) && d.s[d.pos] >= '0' && d.s[d.pos] <= '9' {
+		d.pos++
+	}
+	n, err := strconv.Atoi(d.s[start:d.pos])
+	if err != nil {
+		d.fail()
+	}
+	return n
+}
+
+func (d *itaniumDemangler) sourceName() string {
+	n := d.number()
+	if d.failed || n <= 0 || d.pos+n > len(d.s) {
+		d.fail()
+		return ""
+	}
+
+	name := d.s[d.pos : d.pos+n]
+	d.pos += n
+	if strings.HasPrefix(name, "_GLOBAL__N") {
+		return "(anonymous namespace)"
+	}
+	return name
+}
+
+// unnamedType parses an unnamed type or a lambda.
+func (d *itaniumDemangler) unnamedType() string {
+	var name string
+	if d.consume("Ut") {
+		name = "{unnamed type#"
+	} else if d.consume("Ul") {
+		params := d.params()
+		if !d.consume("E") {
+			d.fail()
+		}
+		name = "{lambda(" + strings.Join(params, ", ") + ")#"
+	} else {
+		d.fail()
+		return ""
+	}
+
+	n := 1
+	if d.peek() != '_' {
+		n = d.number() + 2
+	}
+	if !d.consume("_") {
+		d.fail()
+	}
+	return name + strconv.Itoa(n) + "}"
+}
+
+func (d *itaniumDemangler) operatorName() string {
+	if d.consume("cv") {
+		return "operator " + d.typ()
+	}
+	if d.consume("li") {
+		return "operator\"\" " + d.sourceName()
+	}
+	if d.consume("v") && d.peek() >= '0' && d.peek() <= '9' {
+		d.pos++
+		return "operator " + d.sourceName()
+	}
+
+	if d.pos+2 > len(d.s) {
+		d.fail()
+		return ""
+	}
+	op, ok := itaniumOperators[d.s[d.pos:d.pos+2]]
+	if !ok {
+		d.fail()
+		return ""
+	}
+	d.pos += 2
+	if op[0] >= 'a' && op[0] <= 'z' {
+		return "operator " + op
+	}
+	return "operator" + op
+}
+
+// substitution parses a reference to a previous name or type.
+func (d *itaniumDemangler) substitution() string {
+	if !d.consume("S") {
+		d.fail()
+		return ""
+	}
+
+	if s, ok := itaniumStdSubstitutions[d.peek()]; ok {
+		d.pos++
+		return s
+	}
+
+	i := 0
+	if d.peek() != '_' {
+		for c := d.peek(); c != '_'; c = d.peek() {
+			switch {
+			case c >= '0' && c <= '9':
+				i = i*36 + int(c-'0')
+			case c >= 'A' && c <= 'Z':
+				i = i*36 + int(c-'A') + 10
+			default:
+				d.fail()
+				return ""
+			}
+			d.pos++
+		}
+		i++
+	}
+	d.pos++
+
+	if i >= len(d.subs) {
+		d.fail()
+		return ""
+	}
+	return d.subs[i]
+}
+
+func (d *itaniumDemangler) templateParam() string {
+	if !d.consume("T") {
+		d.fail()
+		return ""
+	}
+
+	i := 0
+	if d.peek() != '_' {
+		i = d.number() + 1
+	}
+	if !d.consume("_") || i >= len(d.templateArgs) {
+		d.fail()
+		return ""
+	}
+
+	arg := d.templateArgs[i]
+	if elements, ok := d.packs[arg]; ok && d.expanding {
+		d.packLength = len(elements)
+		if d.packIndex < len(elements) {
+			return elements[d.packIndex]
+		}
+		return ""
+	}
+	return arg
+}
+
+// withTemplateArgs parses template arguments, and appends them to name.
+func (d *itaniumDemangler) withTemplateArgs(name string) string {
+	d.consume("I")
+
+	// names in the arguments are not constructors
+	last := d.last
+	var args []string
+	for !d.failed && !d.consume("E") {
+		args = append(args, d.templateArg())
+	}
+	d.last = last
+
+	// the template parameters of a function refer to the last arguments outside any type
+	if d.depth == 0 {
+		d.templateArgs = args
+	}
+
+	// e.g. "operator<< <char>"
+	if strings.HasSuffix(name, "<") {
+		name += " "
+	}
+	// empty argument packs are not printed
+	var printed []string
+	for _, arg := range args {
+		if arg != "" {
+			printed = append(printed, arg)
+		}
+	}
+	name += "<" + strings.Join(printed, ", ")
+	if len(args) > 0 && strings.HasSuffix(args[len(args)-1], ">") {
+		name += " "
+	}
+	return name + ">"
+}
+
+func (d *itaniumDemangler) templateArg() string {
+	switch {
+	case d.consume("L"):
+		return d.literal()
+	case d.consume("J"):
+		// argument pack
+		var args []string
+		for !d.failed && !d.consume("E") {
+			args = append(args, d.templateArg())
+		}
+		pack := strings.Join(args, ", ")
+		d.packs[pack] = args
+		return pack
+	case d.peek() == 'X':
+		// expressions
+		d.fail()
+		return ""
+	default:
+		return d.typ()
+	}
+}
+
+// literal parses a literal template argument, after "L".
+func (d *itaniumDemangler) literal() string {
+	if d.consume("_Z") {
+		name, _, _ := d.name()
+		if !d.consume("E") {
+			d.fail()
+		}
+		return name
+	}
+
+	t := d.typ()
+	negative := d.consume("n")
+	start := d.pos
+	for d.pos < len(d.s) && d.s[d.pos] != 'E' {
+		d.pos++
+	}
+	value := d.s[start:d.pos]
+	if !d.consume("E") {
+		d.fail()
+		return ""
+	}
+	if negative {
+		value = "-" + value
+	}
+
+	switch t {
+	case "bool":
+		if value == "0" {
+			return "false"
+		}
+		return "true"
+	case "int":
+		return value
+	case "unsigned int":
+		return value + "u"
+	case "long":
+		return value + "l"
+	case "unsigned long":
+		return value + "ul"
+	default:
+		return "(" + t + ")" + value
+	}
+}
+
+func (d *itaniumDemangler) typ() string {
+	d.depth++
+	defer func() { d.depth-- }()
+
+	c := d.peek()
+	if t, ok := itaniumBuiltinTypes[c]; ok {
+		d.pos++
+		return t
+	}
+
+	var t string
+	switch c {
+	case 'u':
+		d.pos++
+		t = d.sourceName()
+	case 'D':
+		if b, ok := itaniumDBuiltinTypes[d.peekAt(1)]; ok {
+			d.pos += 2
+			return b
+		}
+		switch {
+		case d.consume("Dp"):
+			// pack expansion, e.g. "Args&&..." with Args = <int&, char>
+			start := d.pos
+			d.expanding, d.packIndex, d.packLength = true, 0, 1
+			elements := []string{d.typ()}
+			end, subs := d.pos, len(d.subs)
+			for d.packIndex = 1; d.packIndex < d.packLength && !d.failed; d.packIndex++ {
+				d.pos = start
+				elements = append(elements, d.typ())
+			}
+			if d.packLength == 0 {
+				elements = nil
+			}
+			d.pos, d.subs, d.expanding = end, d.subs[:subs], false
+			t = strings.Join(elements, ", ")
+		case d.consume("DF"):
+			n := d.number()
+			if !d.consume("_") {
+				d.fail()
+			}
+			return "_Float" + strconv.Itoa(n)
+		default:
+			d.fail()
+			return ""
+		}
+	case 'r', 'V', 'K':
+		var qualifiers []string
+		for {
+			if d.consume("r") {
+				qualifiers = append(qualifiers, "restrict")
+			} else if d.consume("V") {
+				qualifiers = append(qualifiers, "volatile")
+			} else if d.consume("K") {
+				qualifiers = append(qualifiers, "const")
+			} else {
+				break
+			}
+		}
+		for i, j := 0, len(qualifiers)-1; i < j; i, j = i+1, j-1 {
+			qualifiers[i], qualifiers[j] = qualifiers[j], qualifiers[i]
+		}
+		t = d.typ()
+		if d.functionTypes[t] {
+			// a qualified member function type, which is not a substitution candidate
+			t += " " + strings.Join(qualifiers, " ")
+			d.functionTypes[t] = true
+			return t
+		}
+		for _, q := range qualifiers {
+			if k, ok := d.declarators[t]; ok {
+				// e.g. "void (* const)(int)"
+				t = t[:k] + " " + q + t[k:]
+				d.declarators[t] = k + 1 + len(q)
+			} else if i := strings.Index(t, " ["); d.arrayTypes[t] && i >= 0 {
+				// qualifies the elements, e.g. "char const [4]"
+				t = t[:i] + " " + q + t[i:]
+				d.arrayTypes[t] = true
+			} else if !strings.HasSuffix(t, " "+q) {
+				// e.g. const T with T = int const
+				t += " " + q
+			}
+		}
+	case 'P':
+		d.pos++
+		t = d.pointerTo(d.typ(), "*")
+	case 'R':
+		d.pos++
+		t = d.pointerTo(d.typ(), "&")
+	case 'O':
+		d.pos++
+		t = d.pointerTo(d.typ(), "&&")
+	case 'F':
+		d.pos++
+		d.consume("Y")
+		result := d.typ()
+		var params []string
+		for !d.failed && d.peek() != 'E' && !((d.peek() == 'R' || d.peek() == 'O') && d.peekAt(1) == 'E') {
+			params = append(params, d.typ())
+		}
+		if len(params) == 1 && params[0] == "void" {
+			params = nil
+		}
+		t = result + " (" + strings.Join(params, ", ") + ")"
+		if d.consume("R") {
+			t += " &"
+		} else if d.consume("O") {
+			t += " &&"
+		}
+		if !d.consume("E") {
+			d.fail()
+		}
+		d.functionTypes[t] = true
+	case 'A':
+		d.pos++
+		var n string
+		if d.peek() != '_' {
+			n = strconv.Itoa(d.number())
+		}
+		if !d.consume("_") {
+			d.fail()
+		}
+		element := d.typ()
+		if i := strings.Index(element, " ["); d.arrayTypes[element] && i >= 0 {
+			// e.g. "int [2][3]"
+			t = element[:i] + " [" + n + "]" + element[i+1:]
+		} else {
+			t = element + " [" + n + "]"
+		}
+		d.arrayTypes[t] = true
+	case 'M':
+		d.pos++
+		class := d.typ()
+		member := d.typ()
+		if d.functionTypes[member] {
+			i := strings.Index(member, " (")
+			t = member[:i] + " (" + class + "::*)" + member[i+1:]
+		} else {
+			t = member + " " + class + "::*"
+		}
+	case 'T':
+		t = d.templateParam()
+		d.subs = append(d.subs, t)
+		if d.peek() != 'I' {
+			return t
+		}
+		t = d.withTemplateArgs(t)
+	case 'S':
+		if d.peekAt(1) == 't' {
+			t, _, _ = d.name()
+			break
+		}
+		t = d.substitution()
+		if d.peek() != 'I' {
+			// substitutions are not candidates again
+			return t
+		}
+		t = d.withTemplateArgs(t)
+	case 'N', 'Z', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		t, _, _ = d.name()
+	default:
+		d.fail()
+		return ""
+	}
+
+	d.subs = append(d.subs, t)
+	return t
+}
+
+// pointerTo formats a pointer or a reference to t.
+func (d *itaniumDemangler) pointerTo(t string, op string) string {
+	// reference collapsing, e.g. with T = int&, T&& is int&
+	ref := t
+	if k, ok := d.declarators[t]; ok {
+		ref = t[:k]
+	}
+	if op != "*" && strings.HasSuffix(ref, "&") {
+		if strings.HasSuffix(ref, "&&") && op == "&" {
+			ret := ref[:len(ref)-1] + t[len(ref):]
+			if _, ok := d.declarators[t]; ok {
+				d.declarators[ret] = len(ref) - 1
+			}
+			return ret
+		}
+		return t
+	}
+
+	var ret string
+	var i int
+	if k, ok := d.declarators[t]; ok {
+		// already a pointer to a function or an array, e.g. "void (*)(int)"
+		ret, i = t[:k]+op+t[k:], k
+	} else if i = strings.Index(t, " ("); d.functionTypes[t] && i >= 0 {
+		ret, i = t[:i]+" ("+op+")"+t[i+1:], i+2
+	} else if i = strings.Index(t, " ["); d.arrayTypes[t] && i >= 0 {
+		ret, i = t[:i]+" ("+op+")"+t[i:], i+2
+	} else {
+		return t + op
+	}
+
+	d.declarators[ret] = i + len(op)
+	return ret
+}
+
+// lastName returns the last part of a qualified name, without template arguments.
+func lastName(name string) string {
+	parts := splitScope(name)
+	name = parts[len(parts)-1]
+	if i := strings.IndexByte(name, '<'); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// isConstructorOrDestructor tests if the last part of a qualified name is a constructor or a destructor.
+func isConstructorOrDestructor(parts []string) bool {
+	if len(parts) < 2 {
+		return false
+	}
+
+	name := strings.TrimPrefix(parts[len(parts)-1], "~")
+	if i := strings.IndexByte(name, '<'); i > 0 {
+		name = name[:i]
+	}
+	class := parts[len(parts)-2]
+	if i := strings.IndexByte(class, '<'); i > 0 {
+		class = class[:i]
+	}
+	return name == class
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// msvcBasicTypes are the one-letter types of the Microsoft C++ ABI.
+var msvcBasicTypes = map[byte]string{
+	'C': "signed char", 'D': "char", 'E': "unsigned char", 'F': "short", 'G': "unsigned short", 'H': "int",
+	'I': "unsigned int", 'J': "long", 'K': "unsigned long", 'M': "float", 'N': "double", 'O': "long double",
+	'X': "void",
+}
+
+// msvcExtendedTypes are the types starting with "_".
+var msvcExtendedTypes = map[byte]string{
+	'N': "bool", 'J': "__int64", 'K': "unsigned __int64", 'W': "wchar_t", 'S': "char16_t", 'U': "char32_t",
+	'Q': "char8_t",
+}
+
+// msvcCallingConventions maps calling convention codes to their keywords.
+var msvcCallingConventions = map[byte]string{
+	'A': "__cdecl", 'B': "__cdecl", 'C': "__pascal", 'D': "__pascal", 'E': "__thiscall", 'F': "__thiscall",
+	'G': "__stdcall", 'H': "__stdcall", 'I': "__fastcall", 'J': "__fastcall", 'M': "__clrcall", 'N': "__clrcall",
+	'Q': "__vectorcall",
+}
+
+// msvcSpecialNames maps the codes after "?" and "?_" to operators and other special names. Constructors and
+// destructors are handled separately.
+var msvcSpecialNames = map[string]string{
+	"2": "operator new", "3": "operator delete", "4": "operator=", "5": "operator>>", "6": "operator<<",
+	"7": "operator!", "8": "operator==", "9": "operator!=", "A": "operator[]", "C": "operator->", "D": "operator*",
+	"E": "operator++", "F": "operator--", "G": "operator-", "H": "operator+", "I": "operator&", "J": "operator->*",
+	"K": "operator/", "L": "operator%", "M": "operator<", "N": "operator<=", "O": "operator>", "P": "operator>=",
+	"Q": "operator,", "R": "operator()", "S": "operator~", "T": "operator^", "U": "operator|", "V": "operator&&",
+	"W": "operator||", "X": "operator*=", "Y": "operator+=", "Z": "operator-=", "_0": "operator/=",
+	"_1": "operator%=", "_2": "operator>>=", "_3": "operator<<=", "_4": "operator&=", "_5": "operator|=",
+	"_6": "operator^=", "_7": "`vftable'", "_8": "`vbtable'", "_U": "operator new[]", "_V": "operator delete[]",
+}
+
+// msvcFunctionClasses describes the function class codes of member and global functions.
+var msvcFunctionClasses = map[byte]struct {
+	access string
+	kind   string // "static", "virtual" or ""
+	member bool   // has a this pointer
+}{
+	'A': {"private", "", true}, 'B': {"private", "", true}, 'C': {"private", "static", false},
+	'D': {"private", "static", false}, 'E': {"private", "virtual", true}, 'F': {"private", "virtual", true},
+	'I': {"protected", "", true}, 'J': {"protected", "", true}, 'K': {"protected", "static", false},
+	'L': {"protected", "static", false}, 'M': {"protected", "virtual", true}, 'N': {"protected", "virtual", true},
+	'Q': {"public", "", true}, 'R': {"public", "", true}, 'S': {"public", "static", false},
+	'T': {"public", "static", false}, 'U': {"public", "virtual", true}, 'V': {"public", "virtual", true},
+	'Y': {"", "", false}, 'Z': {"", "", false},
+}
+
+// msvcDemangler decodes a name mangled with the Microsoft C++ ABI. The output looks like undname's. Anything uncommon
+// in exported symbols (thunks, RTTI descriptors, arrays...) fails the whole name.
+type msvcDemangler struct {
+	s      string
+	pos    int
+	failed bool
+
+	names  []string        // name back references
+	types  []string        // parameter type back references
+	arrays map[string]bool // array types, so pointers to them are formatted as such
+}
+
+// demangleMSVC demangles name, if it is a Microsoft C++ mangled name.
+func demangleMSVC(name string) (*demangledName, bool) {
+	if !strings.HasPrefix(name, "?") {
+		return nil, false
+	}
+
+	d := &msvcDemangler{s: name, pos: 1, arrays: map[string]bool{}}
+	parts, special := d.qualifiedName(true)
+	if d.failed {
+		return nil, false
+	}
+	ret := &demangledName{
+		Scope: parts[:len(parts)-1],
+		Name:  parts[len(parts)-1],
+	}
+	qualified := strings.Join(parts, "::")
+
+	switch c := d.next(); {
+	case c >= '0' && c <= '4':
+		// a variable
+		access := map[byte]string{'0': "private: static ", '1': "protected: static ", '2': "public: static "}[c]
+		t := d.typ()
+		d.cvQualifiers()
+		ret.Signature = access + t + " " + qualified
+		ret.Data = true
+	case c == '6' || c == '7':
+		// a virtual table
+		if special == "" || !d.consume("B") || !d.consume("@") {
+			d.fail()
+		}
+		ret.Signature = "const " + qualified
+		ret.Data = true
+	default:
+		class, ok := msvcFunctionClasses[c]
+		if !ok {
+			d.fail()
+			break
+		}
+
+		var cv string
+		if class.member {
+			d.consume("E") // __ptr64
+			d.consume("I") // __restrict
+			cv = d.cvQualifiers()
+		}
+		signature := d.function(qualified, special == "constructor" || special == "destructor")
+		if cv != "" {
+			signature += " " + cv
+		}
+		if class.kind != "" {
+			signature = class.kind + " " + signature
+		}
+		if class.access != "" {
+			signature = class.access + ": " + signature
+		}
+		ret.Signature = signature
+	}
+
+	if d.failed || d.pos != len(d.s) {
+		return nil, false
+	}
+	return ret, true
+}
+
+func (d *msvcDemangler) fail() {
+	d.failed = true
+	d.pos = len(d.s)
+}
+
+func (d *msvcDemangler) peek() byte {
+	if d.pos < len(d.s) {
+		return d.s[d.pos]
+	}
+	return 0
+}
+
+func (d *msvcDemangler) next() byte {
+	if d.pos < len(d.s) {
+		d.pos++
+		return d.s[d.pos-1]
+	}
+	d.fail()
+	return 0
+}
+
+func (d *msvcDemangler) consume(prefix string) bool {
+	if strings.HasPrefix(d.s[d.pos:], prefix) {
+		d.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+// memorize adds a name for back references.
+func (d *msvcDemangler) memorize(name string) {
+	if len(d.names) >= 10 {
+		return
+	}
+	for _, n := range d.names {
+		if n == name {
+			return
+		}
+	}
+	d.names = append(d.names, name)
+}
+
+// qualifiedName parses a name and its scope, terminated by "@", and returns its parts, outermost first. For the
+// symbol itself, special names are allowed, and the kind of it is returned as well.
+func (d *msvcDemangler) qualifiedName(symbol bool) (parts []string, special string) {
+	var name string
+	switch {
+	case symbol && d.consume("?0"):
+		special = "constructor"
+	case symbol && d.consume("?1"):
+		special = "destructor"
+	case symbol && d.peek() == '?' && !strings.HasPrefix(d.s[d.pos:], "?$"):
+		d.pos++
+		code := string(d.next())
+		if code == "_" {
+			code += string(d.next())
+		}
+		var ok bool
+		name, ok = msvcSpecialNames[code]
+		if !ok {
+			d.fail()
+		}
+		special = "operator"
+	default:
+		name = d.simpleName()
+	}
+
+	// the scope, innermost first
+	for !d.failed && !d.consume("@") {
+		var scope string
+		if d.consume("?A") {
+			// anonymous namespace, e.g. "?A0x12345678@"
+			i := strings.IndexByte(d.s[d.pos:], '@')
+			if i < 0 {
+				d.fail()
+				break
+			}
+			d.memorize(d.s[d.pos-2 : d.pos+i])
+			d.pos += i + 1
+			scope = "`anonymous namespace'"
+		} else if d.peek() == '?' && !strings.HasPrefix(d.s[d.pos:], "?$") {
+			// local scopes are never exported
+			d.fail()
+			break
+		} else {
+			scope = d.simpleName()
+		}
+		parts = append([]string{scope}, parts...)
+	}
+	if d.failed {
+		return nil, ""
+	}
+
+	switch special {
+	case "constructor", "destructor":
+		if len(parts) == 0 {
+			d.fail()
+			return nil, ""
+		}
+		name = parts[len(parts)-1]
+		if i := strings.IndexByte(name, '<'); i >= 0 {
+			name = name[:i]
+		}
+		if special == "destructor" {
+			name = "~" + name
+		}
+	}
+
+	return append(parts, name), special
+}
+
+// simpleName parses a name fragment, a back reference or a template name.
+func (d *msvcDemangler) simpleName() string {
+	c := d.peek()
+	switch {
+	case c >= '0' && c <= '9':
+		d.pos++
+		if int(c-'0') >= len(d.names) {
+			d.fail()
+			return ""
+		}
+		return d.names[c-'0']
+	case d.consume("?$"):
+		// template arguments have their own back references
+		names, types := d.names, d.types
+		d.names, d.types = nil, nil
+
+		name := d.fragment()
+		var args []string
+		for !d.failed && !d.consume("@") {
+			args = append(args, d.templateArg())
+		}
+		d.names, d.types = names, types
+
+		name += "<" + strings.Join(args, ", ") + ">"
+		d.memorize(name)
+		return name
+	default:
+		return d.fragment()
+	}
+}
+
+// fragment parses an identifier terminated by "@".
+func (d *msvcDemangler) fragment() string {
+	i := strings.IndexByte(d.s[d.pos:], '@')
+	if i <= 0 {
+		d.fail()
+		return ""
+	}
+	name := d.s[d.pos : d.pos+i]
+	d.pos += i + 1
+	d.memorize(name)
+	return name
+}
+
+func (d *msvcDemangler) templateArg() string {
+	switch {
+	case d.consume("$0"):
+		return strconv.FormatInt(d.number(), 10)
+	case d.consume("$$V"), d.consume("$$Z"):
+		// empty packs
+		return ""
+	case d.peek() == '$':
+		d.fail()
+		return ""
+	default:
+		return d.typ()
+	}
+}
+
+// number parses an encoded integer.
+func (d *msvcDemangler) number() int64 {
+	negative := d.consume("?")
+
+	var n int64
+	c := d.next()
+	switch {
+	case c >= '0' && c <= '9':
+		n = int64(c-'0') + 1
+	case c >= 'A' && c <= 'P':
+		for ; c != '@'; c = d.next() {
+			if c < 'A' || c > 'P' {
+				d.fail()
+				return 0
+			}
+			n = n*16 + int64(c-'A')
+		}
+	default:
+		d.fail()
+	}
+
+	if negative {
+		return -n
+	}
+	return n
+}
+
+// cvQualifiers parses the qualifiers of a pointee, a variable or a member function.
+func (d *msvcDemangler) cvQualifiers() string {
+	switch d.next() {
+	case 'A':
+		return ""
+	case 'B':
+		return "const"
+	case 'C':
+		return "volatile"
+	case 'D':
+		return "const volatile"
+	default:
+		d.fail()
+		return ""
+	}
+}
+
+// function parses the rest of a function type, starting from the calling convention.
+func (d *msvcDemangler) function(name string, structor bool) string {
+	convention, ok := msvcCallingConventions[d.next()]
+	if !ok {
+		d.fail()
+		return ""
+	}
+
+	var result string
+	if d.consume("@") {
+		if !structor {
+			d.fail()
+		}
+	} else {
+		// storage class of a returned class
+		_ = d.consume("?A") || d.consume("?B")
+		result = d.typ() + " "
+	}
+
+	params := d.params()
+
+	// the exception specification
+	if !d.consume("Z") && !d.consume("_E") {
+		d.fail()
+	}
+
+	return result + convention + " " + name + "(" + params + ")"
+}
+
+// params parses a parameter list.
+func (d *msvcDemangler) params() string {
+	if d.consume("X") {
+		return "void"
+	}
+
+	var params []string
+	for !d.failed {
+		if d.consume("@") {
+			break
+		}
+		if d.consume("Z") {
+			params = append(params, "...")
+			break
+		}
+
+		start := d.pos
+		c := d.peek()
+		if c >= '0' && c <= '9' {
+			d.pos++
+			if int(c-'0') >= len(d.types) {
+				d.fail()
+				break
+			}
+			params = append(params, d.types[c-'0'])
+			continue
+		}
+
+		t := d.typ()
+		if d.pos-start > 1 && len(d.types) < 10 {
+			d.types = append(d.types, t)
+		}
+		params = append(params, t)
+	}
+
+	return strings.Join(params, ", ")
+}
+
+func (d *msvcDemangler) typ() string {
+	c := d.next()
+	if t, ok := msvcBasicTypes[c]; ok {
+		return t
+	}
+
+	switch c {
+	case '_':
+		t, ok := msvcExtendedTypes[d.next()]
+		if !ok {
+			d.fail()
+		}
+		return t
+	case 'P', 'Q', 'R', 'S':
+		// the pointer itself might be qualified
+		pointer := map[byte]string{'P': " *", 'Q': " * const", 'R': " * volatile", 'S': " * const volatile"}[c]
+		if d.consume("6") {
+			return d.functionPointer(pointer)
+		}
+		return d.pointerTo(d.pointee(), pointer)
+	case 'A':
+		return d.pointerTo(d.pointee(), " &")
+	case 'B':
+		return d.pointerTo(d.pointee(), " & volatile")
+	case 'Y':
+		// an array, e.g. "Y01H" for "int [2][2]"
+		n := d.number()
+		var dimensions string
+		for i := int64(0); i < n && !d.failed; i++ {
+			dimensions += "[" + strconv.FormatInt(d.number(), 10) + "]"
+		}
+		t := d.typ() + " " + dimensions
+		d.arrays[t] = true
+		return t
+	case 'V', 'U', 'T':
+		keyword := map[byte]string{'V': "class ", 'U': "struct ", 'T': "union "}[c]
+		parts, _ := d.qualifiedName(false)
+		return keyword + strings.Join(parts, "::")
+	case 'W':
+		if !d.consume("4") {
+			d.fail()
+		}
+		parts, _ := d.qualifiedName(false)
+		return "enum " + strings.Join(parts, "::")
+	case '$':
+		switch {
+		case d.consume("$Q"):
+			return d.pointerTo(d.pointee(), " &&")
+		case d.consume("$T"):
+			return "std::nullptr_t"
+		case d.consume("$A6"):
+			return d.functionPointer("")
+		case d.consume("$B"):
+			d.fail()
+			return ""
+		default:
+			d.fail()
+			return ""
+		}
+	default:
+		d.fail()
+		return ""
+	}
+}
+
+// pointee parses the qualifiers and the type pointed to by a pointer or a reference.
+func (d *msvcDemangler) pointee() string {
+	d.consume("E") // __ptr64
+	d.consume("I") // __restrict
+	d.consume("F") // __unaligned
+	cv := d.cvQualifiers()
+	t := d.typ()
+	if cv == "" {
+		return t
+	}
+	if i := strings.Index(t, " ["); d.arrays[t] && i >= 0 {
+		// qualifies the elements
+		t = t[:i] + " " + cv + t[i:]
+		d.arrays[t] = true
+		return t
+	}
+	return t + " " + cv
+}
+
+// pointerTo formats a pointer or a reference to t, e.g. "int *" or "int (&)[3]".
+func (d *msvcDemangler) pointerTo(t string, pointer string) string {
+	if i := strings.Index(t, " ["); d.arrays[t] && i >= 0 {
+		return t[:i] + " (" + strings.TrimPrefix(pointer, " ") + ")" + t[i+1:]
+	}
+	return t + pointer
+}
+
+// functionPointer parses a function type pointed to, after "6".
+func (d *msvcDemangler) functionPointer(pointer string) string {
+	convention, ok := msvcCallingConventions[d.next()]
+	if !ok {
+		d.fail()
+		return ""
+	}
+	result := d.typ()
+	params := d.params()
+	if !d.consume("Z") {
+		d.fail()
+	}
+	return result + " (" + convention + pointer + ")(" + params + ")"
+}
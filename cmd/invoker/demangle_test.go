@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/jamesits/goinvoke/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDemangle(t *testing.T) {
+	tests := []struct {
+		mangled   string
+		signature string
+		fieldName string
+		data      bool
+	}{
+		// Itanium, compared with c++filt
+		{"_ZN3Bar3FooEi", "Bar::Foo(int)", "BarFoo", false},
+		{"_ZN3BarC2Ev", "Bar::Bar()", "BarCtor", false},
+		{"_ZN3BarD1Ev", "Bar::~Bar()", "BarDtor", false},
+		{"_ZN3BarplERKS_", "Bar::operator+(Bar const&)", "BarOpAdd", false},
+		{"_ZNK3Bar1gEPKcRS_", "Bar::g(char const*, Bar&) const", "BarG", false},
+		{"_ZN2ns1xE", "ns::x", "NsX", true},
+		{"_ZTV3Bar", "vtable for Bar", "BarVtable", true},
+		{"_Z1fPFviE", "f(void (*)(int))", "F", false},
+		{"_Z3maxIiET_S0_S0_", "int max<int>(int, int)", "Max", false},
+		{"_Z1fRA3_Ki", "f(int const (&) [3])", "F", false},
+		{"_ZNSt6vectorIiSaIiEE9push_backERKi", "std::vector<int, std::allocator<int> >::push_back(int const&)", "StdVectorPushback", false},
+		{"_ZN12_GLOBAL__N_13fooEv", "(anonymous namespace)::foo()", "Foo", false},
+
+		// Microsoft, compared with llvm-undname
+		{"?Foo@Bar@@QEAAXH@Z", "public: void __cdecl Bar::Foo(int)", "BarFoo", false},
+		{"??0Bar@@QEAA@XZ", "public: __cdecl Bar::Bar(void)", "BarCtor", false},
+		{"??1Bar@@UEAA@XZ", "public: virtual __cdecl Bar::~Bar(void)", "BarDtor", false},
+		{"??HBar@@QEAA?AV0@AEBV0@@Z", "public: class Bar __cdecl Bar::operator+(class Bar const &)", "BarOpAdd", false},
+		{"?g@Bar@@QEBAHPEBDAEAV1@@Z", "public: int __cdecl Bar::g(char const *, class Bar &) const", "BarG", false},
+		{"?x@@3HA", "int x", "X", true},
+		{"?y@Bar@@2HA", "public: static int Bar::y", "BarY", true},
+		{"??_7Bar@@6B@", "const Bar::`vftable'", "BarVftable", true},
+		{"?f@@YAXP6AXH@Z@Z", "void __cdecl f(void (__cdecl *)(int))", "F", false},
+		{"?h@@YGHPAD@Z", "int __stdcall h(char *)", "H", false},
+		{"?q@@YAXAEAY02H@Z", "void __cdecl q(int (&)[3])", "Q", false},
+		{"?a@@YAPEAUS@@AEBV?$vector@HV?$allocator@H@std@@@std@@@Z", "struct S * __cdecl a(class std::vector<int, class std::allocator<int>> const &)", "A", false},
+	}
+
+	for _, test := range tests {
+		n, ok := demangle(test.mangled)
+		if !assert.True(t, ok, test.mangled) {
+			continue
+		}
+		assert.EqualValues(t, test.signature, n.Signature, test.mangled)
+		assert.EqualValues(t, test.fieldName, n.FieldName(utils.FormatPublicType), test.mangled)
+		assert.EqualValues(t, test.data, n.Data, test.mangled)
+	}
+
+	for _, name := range []string{"deflate", "_Z", "?", "_ZN3Bar", "?Foo@Bar@@QEAAXH"} {
+		_, ok := demangle(name)
+		assert.False(t, ok, name)
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"debug/elf"
+	"flag"
+	"fmt"
+	"github.com/jamesits/goinvoke/utils"
+	"github.com/saferwall/pe"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// binaryInfo is what the dynamic loader reads from a binary to find its dependencies.
+type binaryInfo struct {
+	Format  string
+	Machine string // the loader skips binaries built for other machines
+	Needed  []string
+	Delayed []string // PE only: delay-loaded DLLs, which are only loaded when they are used
+	RPath   []string // ELF only, with $ORIGIN expanded
+	RunPath []string // ELF only, with $ORIGIN expanded
+}
+
+// readBinaryInfo reads the dependencies of the binary at path, without loading it.
+func readBinaryInfo(path string) (*binaryInfo, error) {
+	format, err := detectFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case formatELF:
+		return readELFDependencies(path)
+	case formatPE:
+		return readPEDependencies(path)
+	default:
+		return nil, fmt.Errorf("the dependencies of %s files are not supported", format)
+	}
+}
+
+// readELFDependencies reads DT_NEEDED, DT_RPATH and DT_RUNPATH.
+func readELFDependencies(path string) (*binaryInfo, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info := &binaryInfo{
+		Format:  formatELF,
+		Machine: fmt.Sprintf("%v %v %v", f.Class, f.Data, f.Machine),
+	}
+	info.Needed, err = f.ImportedLibraries()
+	if err != nil {
+		return nil, err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	rpath, err := f.DynString(elf.DT_RPATH)
+	if err != nil {
+		return nil, err
+	}
+	runpath, err := f.DynString(elf.DT_RUNPATH)
+	if err != nil {
+		return nil, err
+	}
+	info.RPath = expandSearchPath(rpath, filepath.Dir(abs))
+	info.RunPath = expandSearchPath(runpath, filepath.Dir(abs))
+
+	return info, nil
+}
+
+// expandSearchPath splits DT_RPATH or DT_RUNPATH entries into directories, and expands $ORIGIN in them. Other
+// dynamic string tokens ($LIB, $PLATFORM) are left as is.
+func expandSearchPath(entries []string, origin string) (ret []string) {
+	for _, entry := range entries {
+		for _, dir := range strings.Split(entry, ":") {
+			dir = strings.ReplaceAll(dir, "${ORIGIN}", origin)
+			dir = strings.ReplaceAll(dir, "$ORIGIN", origin)
+			if dir != "" {
+				ret = append(ret, dir)
+			}
+		}
+	}
+	return
+}
+
+// readPEDependencies reads the import directory and the delay-load import directory.
+func readPEDependencies(path string) (*binaryInfo, error) {
+	f, err := pe.New(path, &pe.Options{
+		OmitExportDirectory:    true,
+		OmitResourceDirectory:  true,
+		OmitSecurityDirectory:  true,
+		OmitRelocDirectory:     true,
+		OmitExceptionDirectory: true,
+		OmitDebugDirectory:     true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	err = f.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the DLL: %w", err)
+	}
+
+	info := &binaryInfo{
+		Format:  formatPE,
+		Machine: fmt.Sprintf("%#x", uint16(f.NtHeader.FileHeader.Machine)),
+	}
+	for _, i := range f.Imports {
+		info.Needed = append(info.Needed, i.Name)
+	}
+	for _, i := range f.DelayImports {
+		info.Delayed = append(info.Delayed, i.Name)
+	}
+
+	return info, nil
+}
+
+// dependency notes
+const (
+	noteAPISet      = "API set"
+	noteDelayLoaded = "delay-loaded"
+)
+
+// depNode is a binary in the dependency tree.
+type depNode struct {
+	Name     string // as the parent refers to it
+	Path     string // empty if not found
+	Note     string
+	Children []*depNode // only filled the first time a binary appears in the tree
+
+	optional bool // a delay-loaded DLL, which does not stop its parent from being loaded if it is missing
+	info     *binaryInfo
+	rpath    []string // ELF only: the DT_RPATH of this binary and of the ones which loaded it, if they have no DT_RUNPATH
+}
+
+// depResolver finds dependencies the way the dynamic loader of their format does.
+type depResolver struct {
+	ExtraDirs []string // searched last
+
+	infos         map[string]*binaryInfo // by path; nil if the file is not a binary
+	loaded        map[string]string      // the path each dependency name was found at, like the loader's module list
+	ldCache       map[string][]string
+	ldLibraryPath []string
+	peDirs        []string
+}
+
+// resolve builds the dependency tree of the binary at path, with the search path of this system.
+func (r *depResolver) resolve(path string) (*depNode, error) {
+	info, err := readBinaryInfo(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r.infos = map[string]*binaryInfo{path: info}
+	if info.Format == formatELF {
+		r.ldCache, err = readLdCache()
+		if err != nil {
+			log.Printf("warning: unable to read %s: %v", ldCachePath, err)
+		}
+		r.ldLibraryPath = utils.PathsFromEnvironmentVariable("LD_LIBRARY_PATH")
+	} else {
+		r.peDirs = peSearchPath(path, info.Machine)
+	}
+
+	return r.tree(path), nil
+}
+
+// tree builds the dependency tree of the binary at path, whose info must be known. Like the loaders do, dependencies
+// are found breadth-first, and once a name is found, it is not searched for again.
+func (r *depResolver) tree(path string) *depNode {
+	info := r.infos[path]
+	r.loaded = map[string]string{}
+
+	root := &depNode{Name: filepath.Base(path), Path: path, info: info}
+	if len(info.RunPath) == 0 {
+		root.rpath = info.RPath
+	}
+	expanded := map[string]bool{path: true}
+	queue := []*depNode{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		for i, name := range append(append([]string{}, n.info.Needed...), n.info.Delayed...) {
+			child := &depNode{Name: name, optional: i >= len(n.info.Needed)}
+			n.Children = append(n.Children, child)
+
+			var apiSet bool
+			child.Path, apiSet = r.find(name, n)
+			switch {
+			case apiSet:
+				child.Note = noteAPISet
+			case child.optional:
+				child.Note = noteDelayLoaded
+			}
+			if child.Path == "" || expanded[child.Path] {
+				continue
+			}
+
+			expanded[child.Path] = true
+			child.info = r.infos[child.Path]
+			if len(child.info.RunPath) == 0 {
+				child.rpath = child.info.RPath
+			}
+			child.rpath = append(append([]string{}, child.rpath...), n.rpath...)
+			queue = append(queue, child)
+		}
+	}
+
+	return root
+}
+
+// find returns the path of a dependency of parent. API sets are virtual DLLs, mapped to real ones by Windows.
+func (r *depResolver) find(name string, parent *depNode) (path string, apiSet bool) {
+	key := name
+	if parent.info.Format == formatPE {
+		key = strings.ToLower(name)
+		if strings.HasPrefix(key, "api-") || strings.HasPrefix(key, "ext-") {
+			return "", true
+		}
+	}
+	if p, ok := r.loaded[key]; ok {
+		return p, false
+	}
+
+	if parent.info.Format == formatELF {
+		path = r.findELF(name, parent)
+	} else {
+		path = r.findPE(name, parent.info.Machine)
+	}
+	if path != "" {
+		r.loaded[key] = path
+	}
+	return path, false
+}
+
+// findELF searches for a shared object the way ld.so(8) does: DT_RPATH (unless there is a DT_RUNPATH),
+// LD_LIBRARY_PATH, DT_RUNPATH, ld.so.cache, then the default directories.
+func (r *depResolver) findELF(name string, parent *depNode) string {
+	if strings.ContainsRune(name, '/') {
+		if r.compatible(name, parent.info.Machine) {
+			return name
+		}
+		return ""
+	}
+
+	var dirs []string
+	if len(parent.info.RunPath) == 0 {
+		dirs = append(dirs, parent.rpath...)
+	}
+	dirs = append(dirs, r.ldLibraryPath...)
+	dirs = append(dirs, parent.info.RunPath...)
+	for _, dir := range dirs {
+		if p := filepath.Join(dir, name); dir != "" && r.compatible(p, parent.info.Machine) {
+			return p
+		}
+	}
+
+	for _, p := range r.ldCache[name] {
+		if r.compatible(p, parent.info.Machine) {
+			return p
+		}
+	}
+
+	for _, dir := range append(append([]string{}, utils.SharedObjectDefaultPaths...), r.ExtraDirs...) {
+		if p := filepath.Join(dir, name); r.compatible(p, parent.info.Machine) {
+			return p
+		}
+	}
+	return ""
+}
+
+// peSearchPath returns the directories Windows searches for the DLLs of the binary at path, in the standard search
+// order for desktop applications. System directories are only known on Windows.
+func peSearchPath(path string, machine string) []string {
+	dirs := []string{filepath.Dir(path)}
+	if runtime.GOOS != "windows" {
+		return dirs
+	}
+
+	if system32, err := utils.GetSystemDirectory(); err == nil {
+		// System32 is redirected for 32-bit processes
+		wow64 := filepath.Join(filepath.Dir(system32), "SysWOW64")
+		if s, err := os.Stat(wow64); err == nil && s.IsDir() && machine == fmt.Sprintf("%#x", uint16(pe.ImageFileMachineI386)) {
+			system32 = wow64
+		}
+		dirs = append(dirs, system32, filepath.Dir(system32))
+	}
+	dirs = append(dirs, ".")
+	dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+	return dirs
+}
+
+// findPE searches for a DLL, with case-insensitive file names.
+func (r *depResolver) findPE(name string, machine string) string {
+	for _, dir := range append(append([]string{}, r.peDirs...), r.ExtraDirs...) {
+		if p := findFileFold(dir, name); p != "" && r.compatible(p, machine) {
+			return p
+		}
+	}
+	return ""
+}
+
+// findFileFold returns the path of the file in dir, whose name is equal to name under Unicode case-folding.
+func findFileFold(dir, name string) string {
+	if dir == "" {
+		return ""
+	}
+	p := filepath.Join(dir, name)
+	if s, err := os.Stat(p); err == nil && s.Mode().IsRegular() {
+		return p
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if strings.EqualFold(e.Name(), name) && e.Type().IsRegular() {
+			return filepath.Join(dir, e.Name())
+		}
+	}
+	return ""
+}
+
+// compatible tests if the file at path is a binary for the machine. The loaders skip other files.
+func (r *depResolver) compatible(path string, machine string) bool {
+	info, ok := r.infos[path]
+	if !ok {
+		if s, err := os.Stat(path); err == nil && s.Mode().IsRegular() {
+			info, _ = readBinaryInfo(path)
+		}
+		r.infos[path] = info
+	}
+
+	return info != nil && info.Machine == machine
+}
+
+// printDependencies prints the dependency tree, marking missing dependencies with "!", and returns whether a
+// dependency which is not delay-loaded is missing.
+func printDependencies(w io.Writer, n *depNode, depth int) (missing bool, err error) {
+	if depth == 0 {
+		_, err = fmt.Fprintf(w, "  %s\n", n.Path)
+	} else {
+		marker, where := " ", n.Path
+		switch {
+		case n.Note == noteAPISet:
+			where = noteAPISet
+		case n.Path == "":
+			marker, where = "!", "not found"
+			missing = !n.optional
+		}
+		if n.optional {
+			where += " (" + noteDelayLoaded + ")"
+		}
+		_, err = fmt.Fprintf(w, "%s %s%s => %s\n", marker, strings.Repeat("    ", depth), n.Name, where)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, child := range n.Children {
+		m, err := printDependencies(w, child, depth+1)
+		if err != nil {
+			return false, err
+		}
+		missing = missing || m
+	}
+	return missing, nil
+}
+
+// depsMain implements `invoker deps`, and returns the exit code.
+func depsMain(args []string) int {
+	var extraDirs stringList
+	flags := flag.NewFlagSet("deps", flag.ExitOnError)
+	flags.Var(&extraDirs, "path", "additional `directory` to search for dependencies, after the usual ones; can be repeated")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s deps [-path dir] file.dll\n\n"+
+			"Prints the dependency tree of a PE or ELF binary without loading it, and exits with 1 if a dependency is missing.\n\n", selfExecutableName)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+
+	p, code, err := resolveLibraryPath(flags.Arg(0))
+	if err != nil {
+		log.Print(err)
+		return code
+	}
+
+	r := &depResolver{ExtraDirs: extraDirs}
+	root, err := r.resolve(p)
+	if err != nil {
+		log.Printf("unable to read the dependencies of \"%s\": %v\n", p, err)
+		return 65
+	}
+
+	missing, err := printDependencies(os.Stdout, root, 0)
+	if err != nil {
+		log.Printf("unable to print the dependencies: %v\n", err)
+		return 74
+	}
+	if missing {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandSearchPath(t *testing.T) {
+	assert.EqualValues(t, []string{"/app/lib", "/app/../lib", "/opt/lib"},
+		expandSearchPath([]string{"$ORIGIN/lib:${ORIGIN}/../lib:", "/opt/lib"}, "/app"))
+}
+
+// depFixture creates empty files for the binaries, and a resolver which already knows them. Paths are relative to a
+// temporary directory, and "/" separated.
+func depFixture(t *testing.T, infos map[string]*binaryInfo) (*depResolver, func(string) string) {
+	dir := t.TempDir()
+	abs := func(p string) string {
+		return filepath.Join(dir, filepath.FromSlash(p))
+	}
+
+	r := &depResolver{infos: map[string]*binaryInfo{}}
+	for p, info := range infos {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(abs(p)), 0777))
+		assert.NoError(t, os.WriteFile(abs(p), nil, 0666))
+		for i := range info.RPath {
+			info.RPath[i] = abs(info.RPath[i])
+		}
+		for i := range info.RunPath {
+			info.RunPath[i] = abs(info.RunPath[i])
+		}
+		r.infos[abs(p)] = info
+	}
+	return r, abs
+}
+
+func TestResolveELF(t *testing.T) {
+	const x64, x86 = "ELFCLASS64 ELFDATA2LSB EM_X86_64", "ELFCLASS32 ELFDATA2LSB EM_386"
+	r, abs := depFixture(t, map[string]*binaryInfo{
+		"app/tool":        {Format: formatELF, Machine: x64, Needed: []string{"liba.so", "libb.so", "libgone.so"}, RPath: []string{"app/lib", "app/deep"}},
+		"app/lib/liba.so": {Format: formatELF, Machine: x64, Needed: []string{"libdeep.so", "libc.so.6"}},
+		"app/lib/libb.so": {Format: formatELF, Machine: x64, Needed: []string{"libhidden.so", "liba.so"}, RunPath: []string{"opt/b"}},
+		// inherited DT_RPATH applies to liba.so, but not to libb.so, which has a DT_RUNPATH
+		"app/deep/libdeep.so":   {Format: formatELF, Machine: x64},
+		"app/deep/libhidden.so": {Format: formatELF, Machine: x64},
+		// the ld.so.cache entry for another machine is skipped
+		"lib32/libc.so.6": {Format: formatELF, Machine: x86},
+		"lib64/libc.so.6": {Format: formatELF, Machine: x64},
+	})
+	r.ldCache = map[string][]string{"libc.so.6": {abs("lib32/libc.so.6"), abs("lib64/libc.so.6")}}
+
+	root := r.tree(abs("app/tool"))
+	var b bytes.Buffer
+	missing, err := printDependencies(&b, root, 0)
+	assert.NoError(t, err)
+	assert.True(t, missing)
+	assert.EqualValues(t, strings.ReplaceAll(`  DIR/app/tool
+      liba.so => DIR/app/lib/liba.so
+          libdeep.so => DIR/app/deep/libdeep.so
+          libc.so.6 => DIR/lib64/libc.so.6
+      libb.so => DIR/app/lib/libb.so
+!         libhidden.so => not found
+          liba.so => DIR/app/lib/liba.so
+!     libgone.so => not found
+`, "DIR/", filepath.ToSlash(abs(""))+"/"), filepath.ToSlash(b.String()))
+}
+
+func TestResolvePE(t *testing.T) {
+	r, abs := depFixture(t, map[string]*binaryInfo{
+		"app/foo.dll": {Format: formatPE, Machine: "0x8664",
+			Needed:  []string{"BAR.DLL", "api-ms-win-core-synch-l1-2-0.dll", "KERNEL32.dll"},
+			Delayed: []string{"missing.dll"},
+		},
+		"app/bar.dll":      {Format: formatPE, Machine: "0x8664", Needed: []string{"kernel32.dll"}},
+		"sys/kernel32.dll": {Format: formatPE, Machine: "0x8664"},
+	})
+	r.peDirs = []string{abs("app")}
+	r.ExtraDirs = []string{abs("sys")}
+
+	root := r.tree(abs("app/foo.dll"))
+	var b bytes.Buffer
+	missing, err := printDependencies(&b, root, 0)
+	assert.NoError(t, err)
+	// delay-loaded DLLs are only loaded when they are used
+	assert.False(t, missing)
+	assert.EqualValues(t, strings.ReplaceAll(`  DIR/app/foo.dll
+      BAR.DLL => DIR/app/bar.dll
+          kernel32.dll => DIR/sys/kernel32.dll
+      api-ms-win-core-synch-l1-2-0.dll => API set
+      KERNEL32.dll => DIR/sys/kernel32.dll
+!     missing.dll => not found (delay-loaded)
+`, "DIR/", filepath.ToSlash(abs(""))+"/"), filepath.ToSlash(b.String()))
+}
@@ -0,0 +1,712 @@
+package main
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/jamesits/goinvoke/utils"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// severities of the findings of `invoker doctor`, most severe first
+const (
+	severityError   = iota // the library will not load, or the struct will not be filled
+	severityWarning        // something might fail later, e.g. when a delay-loaded DLL is first used
+)
+
+var severityNames = []string{"error", "warning"}
+
+// finding is a problem found by `invoker doctor`, and what to do about it.
+type finding struct {
+	Severity int
+	Problem  string
+	Remedy   string
+}
+
+// doctor diagnoses why a library would not load or bind on this system, without loading it.
+type doctor struct {
+	Path      string
+	ExtraDirs []string       // searched last for dependencies
+	TypeName  string         // the struct bound to the library, if any
+	Imports   []structImport // the exports the struct needs
+
+	findings []finding
+	mounts   []mountInfo
+}
+
+// report adds a finding.
+func (d *doctor) report(severity int, remedy string, format string, args ...any) {
+	d.findings = append(d.findings, finding{
+		Severity: severity,
+		Problem:  fmt.Sprintf(format, args...),
+		Remedy:   remedy,
+	})
+}
+
+// run checks the library, and returns the findings, most severe first. Checks which depend on a failed one are
+// skipped.
+func (d *doctor) run() []finding {
+	format, ok := d.checkFile()
+	if !ok {
+		return d.findings
+	}
+
+	if d.checkFormat(format) && d.checkArchitecture(format) {
+		d.checkMount(d.Path)
+		if format == formatELF || format == formatPE {
+			r := &depResolver{ExtraDirs: d.ExtraDirs}
+			root, err := r.resolve(d.Path)
+			if err != nil {
+				d.report(severityWarning, "", "unable to read the dependencies of \"%s\": %v", d.Path, err)
+			} else {
+				d.checkDependencies(r, root, map[string]bool{}, map[string]bool{})
+			}
+		}
+	}
+
+	if d.TypeName != "" {
+		d.checkImports()
+	}
+
+	sort.SliceStable(d.findings, func(i, j int) bool {
+		return d.findings[i].Severity < d.findings[j].Severity
+	})
+	return d.findings
+}
+
+// checkFile finds the library, and tests if it can be read.
+func (d *doctor) checkFile() (format string, ok bool) {
+	p, _, err := resolveLibraryPath(d.Path)
+	if err != nil {
+		d.report(severityError, "check the path; bare names are searched for like the loader does, so pass the path to the file if it is elsewhere", "%v", err)
+		return "", false
+	}
+	d.Path = p
+
+	format, err = detectFormat(p)
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		d.report(severityError, "make the file readable by this user (e.g. chmod a+r), and its directories searchable",
+			"\"%s\" cannot be read: permission denied", p)
+		return "", false
+	case err != nil:
+		d.report(severityError, "pass the path to the shared library itself", "\"%s\" is not a shared library: %v", p, err)
+		return "", false
+	}
+	return format, true
+}
+
+// nativeFormat returns the binary format of the shared libraries of this OS.
+func nativeFormat() string {
+	switch runtime.GOOS {
+	case "windows":
+		return formatPE
+	case "darwin", "ios":
+		return formatMachO
+	default:
+		return formatELF
+	}
+}
+
+// checkFormat tests if this OS loads libraries in the format.
+func (d *doctor) checkFormat(format string) bool {
+	if format == formatArchive {
+		d.report(severityError, "load the shared library it belongs to instead, e.g. foo.dll for foo.lib, or libfoo.so for libfoo.a",
+			"\"%s\" is a static archive or an import library, which cannot be loaded", d.Path)
+		return false
+	}
+	if format != nativeFormat() {
+		d.report(severityError, fmt.Sprintf("use a build of the library for %s/%s", runtime.GOOS, runtime.GOARCH),
+			"\"%s\" is a %s file, but %s only loads %s files", d.Path, format, runtime.GOOS, nativeFormat())
+		return false
+	}
+	return true
+}
+
+// checkArchitecture tests if this process can load the library, whose format must be native.
+func (d *doctor) checkArchitecture(format string) bool {
+	var actual, expected string
+	var err error
+	switch format {
+	case formatELF:
+		actual, expected, err = elfArchitecture(d.Path)
+	case formatPE:
+		actual, expected, err = peArchitecture(d.Path)
+	case formatMachO:
+		actual, expected, err = machOArchitecture(d.Path)
+	}
+	if err != nil {
+		d.report(severityError, "the file might be truncated or corrupted; reinstall it", "\"%s\" is not a valid %s file: %v", d.Path, format, err)
+		return false
+	}
+	if expected != "" {
+		d.report(severityError, fmt.Sprintf("use a build of the library for %s/%s, or build the program for the architecture of the library (GOARCH)", runtime.GOOS, runtime.GOARCH),
+			"\"%s\" is built for %s, but this process can only load %s", d.Path, actual, expected)
+		return false
+	}
+	return true
+}
+
+// elfArchitecture returns the architecture of an ELF file, and if this process cannot load it, the one it needs.
+func elfArchitecture(path string) (actual, expected string, err error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	actual = fmt.Sprintf("%v %v %v", f.Class, f.Data, f.Machine)
+	class, data, machine, ok := utils.HostELFMachine()
+	if ok && (f.Class != class || f.Data != data || f.Machine != machine) {
+		expected = fmt.Sprintf("%v %v %v", class, data, machine)
+	}
+	return actual, expected, nil
+}
+
+// peArchitecture returns the machine of a PE file, and if this process cannot load it, the one it needs.
+func peArchitecture(path string) (actual, expected string, err error) {
+	f, err := pe.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	actual = utils.PEMachineName(f.Machine)
+	machine, ok := utils.HostPEMachine()
+	if ok && f.Machine != machine {
+		expected = utils.PEMachineName(machine)
+	}
+	return actual, expected, nil
+}
+
+// machOArchitecture returns the CPU types of a Mach-O file, and if this process cannot load any of them, the one it
+// needs.
+func machOArchitecture(path string) (actual, expected string, err error) {
+	var cpus []macho.Cpu
+	fat, err := macho.OpenFat(path)
+	if err == nil {
+		defer fat.Close()
+		for _, arch := range fat.Arches {
+			cpus = append(cpus, arch.Cpu)
+		}
+	} else if errors.Is(err, macho.ErrNotFat) {
+		f, err := macho.Open(path)
+		if err != nil {
+			return "", "", err
+		}
+		defer f.Close()
+		cpus = append(cpus, f.Cpu)
+	} else {
+		return "", "", err
+	}
+
+	cpu, mismatch := utils.HostMachOCPU()
+	var names []string
+	for _, c := range cpus {
+		mismatch = mismatch && c != cpu
+		names = append(names, c.String())
+	}
+	if mismatch {
+		expected = cpu.String()
+	}
+	return strings.Join(names, ", "), expected, nil
+}
+
+// mountInfo is a mount point, as listed in /proc/self/mountinfo.
+type mountInfo struct {
+	MountPoint string
+	Options    []string
+}
+
+// parseMountInfo parses the mount points in the proc_pid_mountinfo(5) format.
+func parseMountInfo(data string) (ret []mountInfo) {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		ret = append(ret, mountInfo{
+			MountPoint: unescapeMountPath(fields[4]),
+			Options:    strings.Split(fields[5], ","),
+		})
+	}
+	return
+}
+
+// unescapeMountPath decodes the octal escapes of space, tab, newline and backslash in mount point paths.
+func unescapeMountPath(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// mountOf returns the mount the file at path is on. Later mounts hide earlier ones on the same mount point.
+func mountOf(mounts []mountInfo, path string) (ret mountInfo, ok bool) {
+	for _, m := range mounts {
+		if !strings.HasPrefix(path, m.MountPoint) {
+			continue
+		}
+		if rest := path[len(m.MountPoint):]; m.MountPoint != "/" && rest != "" && rest[0] != '/' {
+			continue
+		}
+		if !ok || len(m.MountPoint) >= len(ret.MountPoint) {
+			ret, ok = m, true
+		}
+	}
+	return
+}
+
+// checkMount tests if the file at path is on a filesystem mounted with noexec, which the loader cannot map code from.
+// Only Linux is checked.
+func (d *doctor) checkMount(path string) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	if d.mounts == nil {
+		data, err := os.ReadFile("/proc/self/mountinfo")
+		if err != nil {
+			return
+		}
+		d.mounts = parseMountInfo(string(data))
+	}
+
+	abs, err := filepath.Abs(path)
+	if err == nil {
+		abs, err = filepath.EvalSymlinks(abs)
+	}
+	if err != nil {
+		return
+	}
+	m, ok := mountOf(d.mounts, abs)
+	if !ok {
+		return
+	}
+	for _, o := range m.Options {
+		if o == "noexec" {
+			d.report(severityError, fmt.Sprintf("move the library to a filesystem mounted without noexec, or remount %s with exec", m.MountPoint),
+				"\"%s\" is on %s, which is mounted with noexec, so the loader cannot map its code", abs, m.MountPoint)
+			return
+		}
+	}
+}
+
+// checkDependencies reports the missing dependencies in the tree, and the symbol versions they lack. Each binary is
+// checked once.
+func (d *doctor) checkDependencies(r *depResolver, n *depNode, checked map[string]bool, missing map[string]bool) {
+	var needs map[string]map[string][]string
+	if n.info.Format == formatELF {
+		var err error
+		needs, err = elfVersionNeeds(n.Path)
+		if err != nil {
+			d.report(severityWarning, "", "unable to read the symbol versions \"%s\" needs: %v", n.Path, err)
+		}
+	}
+
+	for _, c := range n.Children {
+		switch {
+		case c.Note == noteAPISet:
+		case c.Path == "":
+			key := c.Name
+			if n.info.Format == formatPE {
+				key = strings.ToLower(key)
+			}
+			if !missing[key] {
+				missing[key] = true
+				d.reportMissing(r, n, c)
+			}
+		default:
+			if len(needs[c.Name]) > 0 {
+				d.checkVersions(n.Path, c, needs[c.Name])
+			}
+			if c.info != nil && !checked[c.Path] {
+				checked[c.Path] = true
+				d.checkMount(c.Path)
+				d.checkDependencies(r, c, checked, missing)
+			}
+		}
+	}
+}
+
+// reportMissing reports a dependency which is not found, and why the files named like it were skipped.
+func (d *doctor) reportMissing(r *depResolver, parent *depNode, n *depNode) {
+	var skipped []string
+	for _, p := range skippedCandidates(r, n.Name, parent.info) {
+		info := r.infos[p]
+		switch {
+		case info != nil:
+			skipped = append(skipped, fmt.Sprintf("%s is built for %s", p, machineName(info)))
+		default:
+			f, err := os.Open(p)
+			if err == nil {
+				_ = f.Close()
+				skipped = append(skipped, fmt.Sprintf("%s is not a shared library", p))
+			} else if errors.Is(err, fs.ErrPermission) {
+				skipped = append(skipped, fmt.Sprintf("%s cannot be read", p))
+			}
+		}
+	}
+	why := ""
+	if len(skipped) > 0 {
+		why = "; skipped " + strings.Join(skipped, ", ")
+	}
+
+	var remedy string
+	if parent.info.Format == formatELF {
+		remedy = "install it, or add the directory it is in to LD_LIBRARY_PATH"
+	} else {
+		remedy = fmt.Sprintf("put it next to \"%s\", or in a directory in PATH", filepath.Base(d.Path))
+	}
+	if n.optional {
+		d.report(severityWarning, remedy, "\"%s\", delay-loaded by \"%s\", is not found, so the functions using it will fail%s", n.Name, parent.Path, why)
+	} else {
+		d.report(severityError, remedy, "\"%s\", needed by \"%s\", is not found%s", n.Name, parent.Path, why)
+	}
+}
+
+// skippedCandidates returns the files the resolver found for a name, but skipped.
+func skippedCandidates(r *depResolver, name string, parent *binaryInfo) (ret []string) {
+	for p, info := range r.infos {
+		base := filepath.Base(p)
+		if base != name && !(parent.Format == formatPE && strings.EqualFold(base, name)) {
+			continue
+		}
+		if info != nil && info.Machine == parent.Machine {
+			continue
+		}
+		if s, err := os.Stat(p); err == nil && s.Mode().IsRegular() {
+			ret = append(ret, p)
+		}
+	}
+	sort.Strings(ret)
+	return
+}
+
+// machineName describes the machine of a binary.
+func machineName(info *binaryInfo) string {
+	if info.Format == formatPE {
+		if m, err := strconv.ParseUint(info.Machine, 0, 16); err == nil {
+			return utils.PEMachineName(uint16(m))
+		}
+	}
+	return info.Machine
+}
+
+// checkVersions reports the symbol versions a binary needs from a dependency, which it does not define.
+func (d *doctor) checkVersions(path string, dep *depNode, needs map[string][]string) {
+	defs, err := elfVersionDefs(dep.Path)
+	if err != nil {
+		d.report(severityWarning, "", "unable to read the symbol versions of \"%s\": %v", dep.Path, err)
+		return
+	}
+
+	for _, v := range missingVersions(needs, defs) {
+		d.report(severityError, fmt.Sprintf("use a build of the library made against an older %s, or a system with a newer one", dep.Name),
+			"\"%s\" needs %s from \"%s\" (for %s), which %s does not provide", path, v, dep.Name, exampleList(needs[v], 3), dep.Path)
+	}
+}
+
+// elfVersionNeeds returns the symbol versions an ELF binary needs from each library, with the symbols needing them.
+func elfVersionNeeds(path string) (map[string]map[string][]string, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	syms, err := f.ImportedSymbols()
+	if errors.Is(err, elf.ErrNoSymbols) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]map[string][]string{}
+	for _, s := range syms {
+		if s.Library == "" || s.Version == "" {
+			continue
+		}
+		if ret[s.Library] == nil {
+			ret[s.Library] = map[string][]string{}
+		}
+		ret[s.Library][s.Version] = append(ret[s.Library][s.Version], s.Name)
+	}
+	return ret, nil
+}
+
+// elfVersionDefs returns the symbol versions an ELF shared object defines.
+func elfVersionDefs(path string) (map[string]bool, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	syms, err := f.DynamicSymbols()
+	if errors.Is(err, elf.ErrNoSymbols) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]bool{}
+	for _, s := range syms {
+		// version definitions themselves are absolute symbols, so versions without any symbol are included
+		if s.Section != elf.SHN_UNDEF && s.Version != "" {
+			ret[s.Version] = true
+		}
+	}
+	return ret, nil
+}
+
+// missingVersions returns the needed versions which are not defined, sorted.
+func missingVersions(needs map[string][]string, defs map[string]bool) (ret []string) {
+	for v := range needs {
+		if !defs[v] {
+			ret = append(ret, v)
+		}
+	}
+	sort.Strings(ret)
+	return
+}
+
+// exampleList joins the first n names, and mentions how many more there are.
+func exampleList(names []string, n int) string {
+	if len(names) <= n {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:n], ", "), len(names)-n)
+}
+
+// structImport is an export a struct field is filled with by goinvoke.Unmarshal.
+type structImport struct {
+	Field   string
+	Name    string // the func tag, or the field name
+	Ordinal uint32 // Windows only: the ordinal tag, which overrides Name; 0 if none
+}
+
+// loadStructImports finds a struct type in a Go package, and returns the exports it needs on this OS.
+func loadStructImports(pattern string, typeName string) ([]structImport, error) {
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedTypes,
+		Tests: false,
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expecting one package, found %d", len(pkgs))
+	}
+	if len(pkgs[0].Errors) > 0 {
+		return nil, pkgs[0].Errors[0]
+	}
+
+	tn, ok := pkgs[0].Types.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type %s in package %s", typeName, pkgs[0].PkgPath)
+	}
+	s, ok := tn.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", typeName)
+	}
+	return structImports(s, runtime.GOOS), nil
+}
+
+// structImports returns the exports goinvoke.Unmarshal fills the fields of a struct with on goos: the exported
+// *Proc, *LazyProc and goinvoke.FunctionPointer fields, unless their goos tag excludes it.
+func structImports(s *types.Struct, goos string) (ret []structImport) {
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+		tag := reflect.StructTag(s.Tag(i))
+		procType := procTypeName(f.Type())
+		if !f.Exported() || procType == "" || !availableOn(tag.Get("goos"), goos) {
+			continue
+		}
+
+		imp := structImport{Field: f.Name(), Name: tag.Get("func")}
+		if imp.Name == "" {
+			imp.Name = f.Name()
+		}
+		// lazy procs are always found by name, function pointers get a Proc if there is an ordinal
+		if ordinal, err := strconv.ParseUint(tag.Get("ordinal"), 10, 32); err == nil && goos == "windows" && procType != "LazyProc" {
+			imp.Ordinal = uint32(ordinal)
+		}
+		ret = append(ret, imp)
+	}
+	return
+}
+
+// procTypeName returns "Proc" or "LazyProc" if t is a pointer to either, from goinvoke or golang.org/x/sys/windows, or
+// "FunctionPointer" if t is goinvoke.FunctionPointer.
+func procTypeName(t types.Type) string {
+	if n, ok := t.(*types.Named); ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == importPath && n.Obj().Name() == "FunctionPointer" {
+		return "FunctionPointer"
+	}
+
+	p, ok := t.(*types.Pointer)
+	if !ok {
+		return ""
+	}
+	n, ok := p.Elem().(*types.Named)
+	if !ok || n.Obj().Pkg() == nil {
+		return ""
+	}
+	if path := n.Obj().Pkg().Path(); path != importPath && path != "golang.org/x/sys/windows" {
+		return ""
+	}
+	if name := n.Obj().Name(); name == "Proc" || name == "LazyProc" {
+		return name
+	}
+	return ""
+}
+
+// availableOn tests a goos tag like goinvoke.Unmarshal does.
+func availableOn(goosTag string, goos string) bool {
+	if goosTag == "" {
+		return true
+	}
+	for _, s := range strings.Split(goosTag, ",") {
+		if strings.TrimSpace(s) == goos {
+			return true
+		}
+	}
+	return false
+}
+
+// checkImports reports the exports the struct needs, which the library does not have.
+func (d *doctor) checkImports() {
+	if len(d.Imports) == 0 {
+		d.report(severityWarning, "", "%s has no exported *Proc, *LazyProc or %s.FunctionPointer field to fill on %s", d.TypeName, selfPackageName, runtime.GOOS)
+		return
+	}
+
+	lib, err := openLibrary(d.Path)
+	if err != nil {
+		d.report(severityWarning, "", "unable to read the exports of \"%s\": %v", d.Path, err)
+		return
+	}
+	d.findings = append(d.findings, missingImports(d.TypeName, d.Imports, lib)...)
+}
+
+// missingImports returns a finding for each export the struct needs, which the library does not have.
+func missingImports(typeName string, imports []structImport, lib *library) (ret []finding) {
+	names := map[string]bool{}
+	ordinals := map[uint32]bool{}
+	for _, s := range lib.Symbols {
+		names[s.Name] = true
+		ordinals[s.Ordinal] = s.Ordinal != 0
+	}
+
+	remedy := fmt.Sprintf("use a version of the library which exports it, regenerate %s from this one with %s, "+
+		"or add a goos tag to the field if it is not available on %s", typeName, selfExecutableName, runtime.GOOS)
+	for _, imp := range imports {
+		switch {
+		case imp.Ordinal != 0 && !ordinals[imp.Ordinal]:
+			ret = append(ret, finding{
+				Severity: severityError,
+				Problem:  fmt.Sprintf("%s.%s needs ordinal %d, which \"%s\" does not export", typeName, imp.Field, imp.Ordinal, lib.Path),
+				Remedy:   remedy,
+			})
+		case imp.Ordinal == 0 && !names[imp.Name]:
+			hint := ""
+			for _, s := range lib.Symbols {
+				if strings.EqualFold(s.Name, imp.Name) {
+					hint = fmt.Sprintf(" (did you mean \"%s\"?)", s.Name)
+					break
+				}
+			}
+			ret = append(ret, finding{
+				Severity: severityError,
+				Problem:  fmt.Sprintf("%s.%s needs \"%s\", which \"%s\" does not export%s", typeName, imp.Field, imp.Name, lib.Path, hint),
+				Remedy:   remedy,
+			})
+		}
+	}
+	return
+}
+
+// printFindings prints the findings as a numbered list.
+func printFindings(w io.Writer, findings []finding) error {
+	for i, f := range findings {
+		_, err := fmt.Fprintf(w, "%d. %s: %s\n", i+1, severityNames[f.Severity], f.Problem)
+		if err == nil && f.Remedy != "" {
+			_, err = fmt.Fprintf(w, "   fix: %s\n", f.Remedy)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// doctorMain implements `invoker doctor`, and returns the exit code.
+func doctorMain(args []string) int {
+	var extraDirs stringList
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	typeName := flags.String("type", "", "also check the exports the struct `type` needs")
+	pkg := flags.String("pkg", ".", "the Go `package` declaring -type")
+	flags.Var(&extraDirs, "path", "additional `directory` to search for dependencies, after the usual ones; can be repeated")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s doctor [-type T [-pkg dir]] [-path dir] file.dll\n\n"+
+			"Explains why a library would not load or bind on this system, without loading it: the file format and\n"+
+			"architecture, permissions, noexec mounts, missing dependencies and symbol versions, and the exports the\n"+
+			"struct needs. Exits with 1 if an error is found.\n\n", selfExecutableName)
+		flags.PrintDefaults()
+	}
+	positional, _ := parseInterspersed(flags, args)
+	if len(positional) != 1 {
+		flags.Usage()
+		return 64
+	}
+
+	d := &doctor{Path: positional[0], ExtraDirs: extraDirs, TypeName: *typeName}
+	if *typeName != "" {
+		var err error
+		d.Imports, err = loadStructImports(*pkg, *typeName)
+		if err != nil {
+			log.Printf("unable to load %s from \"%s\": %v\n", *typeName, *pkg, err)
+			return 66
+		}
+	}
+
+	findings := d.run()
+	var err error
+	if len(findings) == 0 {
+		_, err = fmt.Printf("no problem found with \"%s\"; its initializers might still fail, as they are not run\n", d.Path)
+	} else {
+		err = printFindings(os.Stdout, findings)
+	}
+	if err != nil {
+		log.Printf("unable to print the findings: %v\n", err)
+		return 74
+	}
+	if len(findings) > 0 && findings[0].Severity == severityError {
+		return 1
+	}
+	return 0
+}
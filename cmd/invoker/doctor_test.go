@@ -0,0 +1,224 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeImporter provides packages declaring empty Proc and LazyProc types, and an empty FunctionPointer interface.
+type fakeImporter struct{}
+
+func (fakeImporter) Import(path string) (*types.Package, error) {
+	pkg := types.NewPackage(path, filepath.Base(path))
+	for _, name := range []string{"Proc", "LazyProc"} {
+		tn := types.NewTypeName(token.NoPos, pkg, name, nil)
+		types.NewNamed(tn, types.NewStruct(nil, nil), nil)
+		pkg.Scope().Insert(tn)
+	}
+	tn := types.NewTypeName(token.NoPos, pkg, "FunctionPointer", nil)
+	types.NewNamed(tn, types.NewInterfaceType(nil, nil).Complete(), nil)
+	pkg.Scope().Insert(tn)
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+func TestStructImports(t *testing.T) {
+	const src = `package foo
+
+import (
+	"github.com/jamesits/goinvoke"
+	"golang.org/x/sys/windows"
+)
+
+type Foo struct {
+	ByName      *goinvoke.Proc
+	ByTag       *goinvoke.LazyProc ` + "`func:\"foo_bar\"`" + `
+	ByOrdinal   *windows.Proc      ` + "`func:\"baz\" ordinal:\"12\"`" + `
+	LazyOrdinal *windows.LazyProc  ` + "`ordinal:\"13\"`" + `
+	WindowsOnly *goinvoke.Proc     ` + "`goos:\"windows\"`" + `
+	NotAProc    uintptr
+	private     *goinvoke.Proc
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "foo.go", src, 0)
+	assert.NoError(t, err)
+	pkg, err := (&types.Config{Importer: fakeImporter{}}).Check("foo", fset, []*ast.File{f}, nil)
+	assert.NoError(t, err)
+	s := pkg.Scope().Lookup("Foo").Type().Underlying().(*types.Struct)
+
+	assert.EqualValues(t, []structImport{
+		{Field: "ByName", Name: "ByName"},
+		{Field: "ByTag", Name: "foo_bar"},
+		{Field: "ByOrdinal", Name: "baz"},
+		{Field: "LazyOrdinal", Name: "LazyOrdinal"},
+	}, structImports(s, "linux"))
+	assert.EqualValues(t, []structImport{
+		{Field: "ByName", Name: "ByName"},
+		{Field: "ByTag", Name: "foo_bar"},
+		{Field: "ByOrdinal", Name: "baz", Ordinal: 12},
+		{Field: "LazyOrdinal", Name: "LazyOrdinal"},
+		{Field: "WindowsOnly", Name: "WindowsOnly"},
+	}, structImports(s, "windows"))
+}
+
+func TestStructImportsFunctionPointer(t *testing.T) {
+	// as generated from several DLLs
+	const src = `package foo
+
+import (
+	"github.com/jamesits/goinvoke"
+)
+
+type Foo struct {
+	FooBar   goinvoke.FunctionPointer ` + "`func:\"foo_bar\"`" + `
+	Baz      goinvoke.FunctionPointer ` + "`func:\"baz\" goos:\"linux,windows\"`" + `
+	Ordinal3 goinvoke.FunctionPointer ` + "`ordinal:\"3\" goos:\"windows\"`" + `
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "foo.go", src, 0)
+	assert.NoError(t, err)
+	pkg, err := (&types.Config{Importer: fakeImporter{}}).Check("foo", fset, []*ast.File{f}, nil)
+	assert.NoError(t, err)
+	s := pkg.Scope().Lookup("Foo").Type().Underlying().(*types.Struct)
+
+	assert.EqualValues(t, []structImport{
+		{Field: "FooBar", Name: "foo_bar"},
+	}, structImports(s, "darwin"))
+	assert.EqualValues(t, []structImport{
+		{Field: "FooBar", Name: "foo_bar"},
+		{Field: "Baz", Name: "baz"},
+		{Field: "Ordinal3", Name: "Ordinal3", Ordinal: 3},
+	}, structImports(s, "windows"))
+}
+
+func TestMissingImports(t *testing.T) {
+	lib := &library{Path: "foo.dll", Symbols: []symbol{
+		{Name: "FooBar", Ordinal: 1},
+		{Name: "Baz", Ordinal: 2},
+	}}
+	findings := missingImports("Foo", []structImport{
+		{Field: "FooBar", Name: "FooBar"},
+		{Field: "Foobar", Name: "foobar"},
+		{Field: "Qux", Name: "Qux"},
+		{Field: "ByOrdinal", Name: "ByOrdinal", Ordinal: 2},
+		{Field: "Gone", Name: "Baz", Ordinal: 3},
+	}, lib)
+
+	var problems []string
+	for _, f := range findings {
+		assert.Equal(t, severityError, f.Severity)
+		problems = append(problems, f.Problem)
+	}
+	assert.EqualValues(t, []string{
+		`Foo.Foobar needs "foobar", which "foo.dll" does not export (did you mean "FooBar"?)`,
+		`Foo.Qux needs "Qux", which "foo.dll" does not export`,
+		`Foo.Gone needs ordinal 3, which "foo.dll" does not export`,
+	}, problems)
+}
+
+func TestParseMountInfo(t *testing.T) {
+	mounts := parseMountInfo(`22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+23 22 0:5 / /tmp rw,nosuid,nodev,noexec shared:2 - tmpfs tmpfs rw
+24 22 0:6 / /tmpfoo rw shared:3 - tmpfs tmpfs rw
+25 22 0:7 / /mnt/my\040disk rw,noexec - vfat /dev/sdb1 rw
+26 23 0:8 / /tmp rw - tmpfs tmpfs rw
+`)
+	assert.Len(t, mounts, 5)
+	assert.Equal(t, "/mnt/my disk", mounts[3].MountPoint)
+	assert.Equal(t, []string{"rw", "noexec"}, mounts[3].Options)
+
+	for path, expected := range map[string]string{
+		"/usr/lib/libfoo.so":     "/",
+		"/tmpfoo/libfoo.so":      "/tmpfoo",
+		"/mnt/my disk/libfoo.so": "/mnt/my disk",
+		"/tmp/libfoo.so":         "/tmp",
+	} {
+		m, ok := mountOf(mounts, path)
+		assert.True(t, ok)
+		assert.Equal(t, expected, m.MountPoint, path)
+	}
+	// the later mount on /tmp hides the noexec one
+	m, _ := mountOf(mounts, "/tmp/libfoo.so")
+	assert.Equal(t, []string{"rw"}, m.Options)
+}
+
+func TestMissingVersions(t *testing.T) {
+	assert.EqualValues(t, []string{"GLIBC_2.34", "GLIBC_2.38"}, missingVersions(map[string][]string{
+		"GLIBC_2.2.5": {"malloc"},
+		"GLIBC_2.38":  {"strlcpy"},
+		"GLIBC_2.34":  {"pthread_create"},
+	}, map[string]bool{"GLIBC_2.2.5": true, "GLIBC_2.17": true}))
+
+	assert.Equal(t, "a, b", exampleList([]string{"a", "b"}, 3))
+	assert.Equal(t, "a, b, c and 2 more", exampleList([]string{"a", "b", "c", "d", "e"}, 3))
+}
+
+func TestDoctorDependencies(t *testing.T) {
+	r, abs := depFixture(t, map[string]*binaryInfo{
+		"app/foo.dll": {Format: formatPE, Machine: "0x8664",
+			Needed:  []string{"bar.dll", "api-ms-win-core-synch-l1-2-0.dll"},
+			Delayed: []string{"missing.dll"},
+		},
+		"app/baz.dll": {Format: formatPE, Machine: "0x8664", Needed: []string{"BAR.dll"}},
+		"sys/bar.dll": {Format: formatPE, Machine: "0x14c"},
+	})
+	r.peDirs = []string{abs("app")}
+	r.ExtraDirs = []string{abs("sys")}
+	r.infos[abs("app/foo.dll")].Needed = append(r.infos[abs("app/foo.dll")].Needed, "baz.dll")
+
+	d := &doctor{Path: abs("app/foo.dll")}
+	d.checkDependencies(r, r.tree(abs("app/foo.dll")), map[string]bool{}, map[string]bool{})
+
+	// BAR.dll is only reported once
+	if assert.Len(t, d.findings, 2) {
+		assert.Equal(t, severityError, d.findings[0].Severity)
+		assert.Equal(t, `"bar.dll", needed by "`+abs("app/foo.dll")+`", is not found; skipped `+abs("sys/bar.dll")+` is built for i386 (0x14c)`, d.findings[0].Problem)
+		assert.Equal(t, severityWarning, d.findings[1].Severity)
+		assert.True(t, strings.HasPrefix(d.findings[1].Problem, `"missing.dll", delay-loaded by`))
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	findings := (&doctor{Path: "testdata/foo.lib"}).run()
+	if assert.Len(t, findings, 1) {
+		assert.Contains(t, findings[0].Problem, "import library")
+	}
+
+	if runtime.GOOS != "darwin" {
+		findings = (&doctor{Path: "testdata/libfoo.dylib"}).run()
+		if assert.Len(t, findings, 1) {
+			assert.Contains(t, findings[0].Problem, "is a Mach-O file")
+		}
+	}
+
+	findings = (&doctor{Path: filepath.Join(t.TempDir(), "libnothing.so")}).run()
+	if assert.Len(t, findings, 1) {
+		assert.Contains(t, findings[0].Problem, "not found")
+	}
+}
+
+func TestDoctorHostLibrary(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("ELF only")
+	}
+	const path = "/usr/lib/x86_64-linux-gnu/libz.so.1"
+	if _, err := os.Stat(path); err != nil || runtime.GOARCH != "amd64" {
+		t.Skip("no libz")
+	}
+
+	d := &doctor{Path: path, TypeName: "Libz", Imports: []structImport{
+		{Field: "ZlibVersion", Name: "zlibVersion"},
+		{Field: "Inflate", Name: "inflate"},
+	}}
+	assert.Empty(t, d.run())
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"debug/elf"
+	"errors"
+	"sort"
+)
+
+// readELF reads the exported functions of an ELF shared object.
+func readELF(path string) (*library, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	syms, err := f.DynamicSymbols()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &library{
+		Path:   path,
+		Format: formatELF,
+		GOOS:   "linux",
+	}
+	if f.OSABI == elf.ELFOSABI_FREEBSD {
+		l.GOOS = "freebsd"
+	}
+
+	hidden, err := hiddenVersions(f, len(syms))
+	if err != nil {
+		return nil, err
+	}
+
+	// the same function might be exported multiple times with different versions
+	seen := map[string]bool{}
+	for i, s := range syms {
+		exported, data := isExportedSymbol(s)
+		if !exported || hidden[i] || seen[s.Name] {
+			continue
+		}
+		seen[s.Name] = true
+
+		l.Symbols = append(l.Symbols, symbol{
+			Name:    s.Name,
+			Address: s.Value,
+			Version: s.Version,
+			Data:    data,
+		})
+	}
+
+	// .dynsym is in hash order, which is meaningless to humans
+	sort.Slice(l.Symbols, func(i, j int) bool {
+		return l.Symbols[i].Name < l.Symbols[j].Name
+	})
+
+	return l, nil
+}
+
+// isExportedSymbol tests if s is a function or a variable defined in this shared object, and visible to others.
+func isExportedSymbol(s elf.Symbol) (exported bool, data bool) {
+	// version definitions (e.g. "GLIBC_2.4") are absolute symbols
+	if s.Name == "" || s.Section == elf.SHN_UNDEF || s.Section == elf.SHN_ABS {
+		return false, false
+	}
+
+	switch elf.ST_TYPE(s.Info) {
+	case elf.STT_FUNC, elf.STT_LOOS: // STT_LOOS is STT_GNU_IFUNC on GNU systems
+	case elf.STT_OBJECT:
+		data = true
+	default:
+		return false, false
+	}
+
+	switch elf.ST_BIND(s.Info) {
+	case elf.STB_GLOBAL, elf.STB_WEAK:
+	default:
+		return false, false
+	}
+
+	switch elf.ST_VISIBILITY(s.Other) {
+	case elf.STV_DEFAULT, elf.STV_PROTECTED:
+		return true, data
+	default:
+		return false, false
+	}
+}
+
+// hiddenVersions tests each dynamic symbol for a hidden version (e.g. "memcpy@GLIBC_2.2.5" as opposed to
+// "memcpy@@GLIBC_2.14"), which is kept for compatibility only and is invisible to dlsym(3).
+func hiddenVersions(f *elf.File, count int) ([]bool, error) {
+	ret := make([]bool, count)
+
+	section := f.Section(".gnu.version")
+	if section == nil {
+		return ret, nil
+	}
+	versym, err := section.Data()
+	if err != nil {
+		return nil, err
+	}
+	if len(versym) < (count+1)*2 {
+		return nil, errors.New(".gnu.version is too short")
+	}
+
+	// .gnu.version has an entry for the null symbol, which DynamicSymbols() skips
+	for i := range ret {
+		ret[i] = f.ByteOrder.Uint16(versym[(i+1)*2:])&0x8000 != 0
+	}
+
+	return ret, nil
+}
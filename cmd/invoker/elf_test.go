@@ -0,0 +1,49 @@
+//go:build linux
+
+package main
+
+import (
+	"github.com/jamesits/goinvoke/utils"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestReadELF(t *testing.T) {
+	path, err := utils.FindSharedObject("libm.so.6")
+	assert.NoError(t, err)
+
+	l, err := openLibrary(path)
+	assert.NoError(t, err)
+	assert.EqualValues(t, formatELF, l.Format)
+	assert.EqualValues(t, "linux", l.GOOS)
+
+	names := map[string]bool{}
+	data := map[string]bool{}
+	for _, s := range l.Symbols {
+		assert.False(t, names[s.Name], "duplicated symbol %s", s.Name)
+		names[s.Name] = true
+		data[s.Name] = s.Data
+	}
+	assert.True(t, sort.SliceIsSorted(l.Symbols, func(i, j int) bool { return l.Symbols[i].Name < l.Symbols[j].Name }))
+
+	// a plain function
+	assert.True(t, names["sqrt"])
+	// only available as a compatibility symbol with a hidden version
+	assert.False(t, names["__acos_finite"])
+	// imported, not exported
+	assert.False(t, names["__cxa_finalize"])
+	// a variable
+	assert.True(t, names["signgam"])
+	assert.True(t, data["signgam"])
+	assert.False(t, data["sqrt"])
+	// a version definition
+	assert.False(t, names["GLIBC_2.4"])
+}
+
+func TestLibraryBaseName(t *testing.T) {
+	assert.EqualValues(t, "libz", libraryBaseName("/usr/lib/libz.so.1.3"))
+	assert.EqualValues(t, "libz", libraryBaseName("libz.so"))
+	assert.EqualValues(t, "libsoap", libraryBaseName("libsoap.so.2"))
+	assert.EqualValues(t, "user32", libraryBaseName("user32.dll"))
+}
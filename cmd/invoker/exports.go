@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// output formats of `invoker exports`
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// exportEntry is an export, as listed by `invoker exports`.
+type exportEntry struct {
+	Name      string `json:"name"` // empty for ordinal-only exports
+	Ordinal   uint32 `json:"ordinal,omitempty"`
+	Address   uint64 `json:"address"` // the RVA for PE, the symbol value otherwise
+	Kind      string `json:"kind"`
+	Forwarder string `json:"forwarder,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
+// exportEntries converts the chosen exports for printing.
+func exportEntries(symbols []mergedSymbol) []exportEntry {
+	ret := []exportEntry{}
+	for _, s := range symbols {
+		e := exportEntry{
+			Name:      s.Name,
+			Ordinal:   s.Ordinal,
+			Address:   s.Address,
+			Kind:      kindFunction,
+			Forwarder: s.Forwarder,
+			Version:   s.Version,
+		}
+		if s.Data {
+			e.Kind = kindData
+		}
+		ret = append(ret, e)
+	}
+	return ret
+}
+
+// exportsMain implements `invoker exports`, and returns the exit code.
+func exportsMain(args []string) int {
+	var include, exclude stringList
+	flags := flag.NewFlagSet("exports", flag.ExitOnError)
+	format := flags.String("format", formatTable, "output `format`: \"table\", \"json\" or \"csv\"")
+	symbolsFile := flags.String("symbols", "", "only list the exports listed in the `file`, one per line; missing ones are errors")
+	flags.Var(&include, "include", "only list exports matching the regular `expression`; can be repeated")
+	flags.Var(&exclude, "exclude", "do not list exports matching the regular `expression`; can be repeated")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: %s exports [-format table|json|csv] [-include regexp] [-exclude regexp] file.dll\n\nLists the exports of a PE, ELF or Mach-O library.\n\n", selfExecutableName)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+	if *format != formatTable && *format != formatJSON && *format != formatCSV {
+		log.Printf("unknown format \"%s\"", *format)
+		return 64
+	}
+
+	g := &generator{
+		DLLs:        flags.Args(),
+		Include:     include,
+		Exclude:     exclude,
+		SymbolsFile: *symbolsFile,
+	}
+	libs, code := g.openLibraries()
+	if code != 0 {
+		return code
+	}
+	symbols, _, code := g.symbols(libs)
+	if code != 0 {
+		return code
+	}
+
+	err := printExports(os.Stdout, libs[0].Format, exportEntries(symbols), *format)
+	if err != nil {
+		log.Printf("unable to print the exports: %v\n", err)
+		return 74
+	}
+	return 0
+}
+
+// printExports prints the exports of a library in the given format. Tables only have the columns which make sense for
+// the file format.
+func printExports(w io.Writer, fileFormat string, entries []exportEntry, format string) error {
+	switch format {
+	case formatJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(entries)
+
+	case formatCSV:
+		c := csv.NewWriter(w)
+		_ = c.Write([]string{"name", "ordinal", "address", "kind", "forwarder", "version"})
+		for _, e := range entries {
+			_ = c.Write([]string{e.Name, formatOrdinal(e.Ordinal), formatAddress(e.Address), e.Kind, e.Forwarder, e.Version})
+		}
+		c.Flush()
+		return c.Error()
+	}
+
+	t := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if fileFormat == formatPE {
+		_, _ = fmt.Fprintln(t, "ORDINAL\tRVA\tKIND\tNAME\tFORWARDER")
+		for _, e := range entries {
+			_, _ = fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\n", formatOrdinal(e.Ordinal), formatAddress(e.Address), e.Kind, dashIfEmpty(e.Name), e.Forwarder)
+		}
+	} else {
+		_, _ = fmt.Fprintln(t, "ADDRESS\tKIND\tNAME\tVERSION")
+		for _, e := range entries {
+			_, _ = fmt.Fprintf(t, "%s\t%s\t%s\t%s\n", formatAddress(e.Address), e.Kind, e.Name, e.Version)
+		}
+	}
+	return t.Flush()
+}
+
+// formatOrdinal formats a PE ordinal; unknown ones (e.g. in import libraries, or in other file formats) are empty.
+func formatOrdinal(ordinal uint32) string {
+	if ordinal == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(ordinal), 10)
+}
+
+// formatAddress formats an address in hexadecimal; unknown ones are empty.
+func formatAddress(address uint64) string {
+	if address == 0 {
+		return ""
+	}
+	return fmt.Sprintf("0x%08x", address)
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrintExports(t *testing.T) {
+	entries := exportEntries([]mergedSymbol{
+		{symbol: symbol{Name: "HeapAlloc", Ordinal: 1, Forwarder: "NTDLL.RtlAllocateHeap"}},
+		{symbol: symbol{Name: "foo_init", Ordinal: 3, Address: 0x1010}},
+		{symbol: symbol{Name: "foo_version", Ordinal: 4, Address: 0x3000, Data: true}},
+		{symbol: symbol{Ordinal: 2, Address: 0x1020}},
+	})
+
+	var b bytes.Buffer
+	assert.NoError(t, printExports(&b, formatPE, entries, formatTable))
+	assert.EqualValues(t, `ORDINAL  RVA         KIND      NAME         FORWARDER
+1                    function  HeapAlloc    NTDLL.RtlAllocateHeap
+3        0x00001010  function  foo_init     
+4        0x00003000  data      foo_version  
+2        0x00001020  function  -            
+`, b.String())
+
+	b.Reset()
+	assert.NoError(t, printExports(&b, formatPE, entries[1:3], formatCSV))
+	assert.EqualValues(t, `name,ordinal,address,kind,forwarder,version
+foo_init,3,0x00001010,function,,
+foo_version,4,0x00003000,data,,
+`, b.String())
+
+	b.Reset()
+	assert.NoError(t, printExports(&b, formatELF, exportEntries([]mergedSymbol{
+		{symbol: symbol{Name: "memcpy", Address: 0x9a0c0, Version: "GLIBC_2.14"}},
+	}), formatJSON))
+	assert.JSONEq(t, `[{"name": "memcpy", "address": 630976, "kind": "function", "version": "GLIBC_2.14"}]`, b.String())
+
+	b.Reset()
+	assert.NoError(t, printExports(&b, formatELF, exportEntries(nil), formatJSON))
+	assert.EqualValues(t, "[]\n", b.String())
+}
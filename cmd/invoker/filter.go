@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// symbolFilter chooses which exports are generated.
+type symbolFilter struct {
+	include   []*regexp.Regexp
+	exclude   []*regexp.Regexp
+	allowlist []string // in order of appearance
+}
+
+// newSymbolFilter compiles the include and exclude patterns. Symbols in the allowlist are always kept, and must exist.
+func newSymbolFilter(include, exclude, allowlist []string) (*symbolFilter, error) {
+	f := &symbolFilter{allowlist: allowlist}
+
+	for _, pattern := range include {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern: %w", err)
+		}
+		f.include = append(f.include, r)
+	}
+
+	for _, pattern := range exclude {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+		f.exclude = append(f.exclude, r)
+	}
+
+	return f, nil
+}
+
+// readSymbolList reads one symbol per line, ignoring blank lines and comments starting with "#".
+func readSymbolList(path string) (ret []string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line != "" {
+			ret = append(ret, line)
+		}
+	}
+
+	return ret, scanner.Err()
+}
+
+// filterName is the name patterns and the allowlist match against; ordinal-only exports are written as "@<ordinal>".
+func filterName(s symbol) string {
+	if s.Name == "" {
+		return "@" + strconv.FormatUint(uint64(s.Ordinal), 10)
+	}
+	return s.Name
+}
+
+// apply returns the chosen symbols, sorted. Symbols in the allowlist are always chosen, and are reported as an error
+// if they do not exist. Otherwise, a symbol is chosen if it matches any include pattern (or there is neither an
+// include pattern nor an allowlist), and does not match any exclude pattern.
+func (f *symbolFilter) apply(symbols []mergedSymbol) (ret []mergedSymbol, err error) {
+	listed := map[string]bool{}
+	for _, name := range f.allowlist {
+		listed[name] = true
+	}
+
+	found := map[string]bool{}
+	for _, s := range symbols {
+		name := filterName(s.symbol)
+		if listed[name] {
+			found[name] = true
+			ret = append(ret, s)
+			continue
+		}
+
+		chosen := len(f.include) == 0 && len(f.allowlist) == 0
+		for _, r := range f.include {
+			chosen = chosen || r.MatchString(name)
+		}
+		for _, r := range f.exclude {
+			chosen = chosen && !r.MatchString(name)
+		}
+		if chosen {
+			ret = append(ret, s)
+		}
+	}
+
+	var missing []string
+	for _, name := range f.allowlist {
+		if !found[name] {
+			missing = append(missing, strconv.Quote(name))
+			found[name] = true
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("symbols not found: %s", strings.Join(missing, ", "))
+	}
+
+	sortSymbols(ret)
+	return ret, nil
+}
+
+// sortSymbols sorts symbols by name, with ordinal-only exports last.
+func sortSymbols(symbols []mergedSymbol) {
+	sort.SliceStable(symbols, func(i, j int) bool {
+		a, b := symbols[i].symbol, symbols[j].symbol
+		if (a.Name == "") != (b.Name == "") {
+			return a.Name != ""
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Ordinal < b.Ordinal
+	})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func filterNames(t *testing.T, f *symbolFilter, symbols []mergedSymbol) (names []string) {
+	chosen, err := f.apply(symbols)
+	assert.NoError(t, err)
+	for _, s := range chosen {
+		names = append(names, filterName(s.symbol))
+	}
+	return
+}
+
+func TestSymbolFilter(t *testing.T) {
+	symbols := []mergedSymbol{
+		{symbol: symbol{Name: "MessageBoxW"}},
+		{symbol: symbol{Ordinal: 2}},
+		{symbol: symbol{Name: "MessageBoxA"}},
+		{symbol: symbol{Name: "GetWindowTextW"}},
+		{symbol: symbol{Name: "GetWindowTextA"}},
+	}
+
+	f, err := newSymbolFilter(nil, nil, nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"GetWindowTextA", "GetWindowTextW", "MessageBoxA", "MessageBoxW", "@2"}, filterNames(t, f, symbols))
+
+	f, err = newSymbolFilter([]string{"^MessageBox", "^GetWindow"}, []string{"A$"}, nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"GetWindowTextW", "MessageBoxW"}, filterNames(t, f, symbols))
+
+	list := filepath.Join(t.TempDir(), "symbols.txt")
+	assert.NoError(t, os.WriteFile(list, []byte("# comment\nMessageBoxA\n\n@2 # by ordinal\n"), 0644))
+	allowlist, err := readSymbolList(list)
+	assert.NoError(t, err)
+	f, err = newSymbolFilter(nil, []string{"A$"}, allowlist)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"MessageBoxA", "@2"}, filterNames(t, f, symbols))
+
+	f, err = newSymbolFilter(nil, nil, []string{"MessageBoxW", "MessageBoxExW"})
+	assert.NoError(t, err)
+	_, err = f.apply(symbols)
+	assert.ErrorContains(t, err, "\"MessageBoxExW\"")
+
+	_, err = newSymbolFilter([]string{"("}, nil, nil)
+	assert.Error(t, err)
+}
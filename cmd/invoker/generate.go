@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jamesits/goinvoke/utils"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// output styles
+const (
+	styleStruct   = "struct"   // a struct of procs, filled by Unmarshal
+	styleLazyVars = "lazyvars" // package-level lazy procs, like mkwinsyscall's
+)
+
+// generator generates one file of bindings. Its fields mirror the command line flags.
+type generator struct {
+	DLLs        []string
+	Type        string
+	Output      string
+	TrimPrefix  []string
+	TrimSuffix  []string
+	Rewrite     []string // "pattern=replacement" rules for names
+	Tags        []string
+	Lazy        bool
+	Style       string // styleStruct if empty
+	Header      string
+	Include     []string
+	Exclude     []string
+	Symbols     []string // allowlist, in addition to SymbolsFile
+	SymbolsFile string
+	Rename      map[string]string // symbol name to field name
+	Def         string            // module-definition file naming the ordinal-only exports
+	Template    string            // replaces src.tmpl if set
+
+	SkipForwarders  bool
+	CamelCase       bool
+	Initialisms     bool
+	KeepUnicode     bool
+	KeepUnderscores bool
+
+	Check bool
+
+	// written into the output
+	CommandLineRaw    []string
+	CommandLineCooked []string
+	SelfGenerate      bool
+}
+
+// outputDefaults fills in the type name and the output file name, if they are empty.
+func (g *generator) outputDefaults(dllPath string) {
+	if len(g.Type) == 0 {
+		g.Type = utils.FormatPublicType(libraryBaseName(dllPath))
+	}
+
+	if len(g.Output) == 0 {
+		g.Output = filepath.Join(".", strings.ToLower(libraryBaseName(dllPath))+"_dll.go")
+	}
+}
+
+// openLibraries resolves and parses all the DLLs.
+func (g *generator) openLibraries() ([]*library, int) {
+	var libs []*library
+	for _, p := range g.DLLs {
+		p, code, err := resolveLibraryPath(p)
+		if err != nil {
+			log.Print(err)
+			return nil, code
+		}
+
+		lib, err := openLibrary(p)
+		if err != nil {
+			log.Printf("unable to read the DLL \"%s\": %v\n", p, err)
+			return nil, 65
+		}
+		libs = append(libs, lib)
+	}
+
+	if g.Def != "" {
+		m, err := readModuleDefinition(g.Def)
+		if err != nil {
+			log.Printf("unable to read the module definition: %v\n", err)
+			return nil, 66
+		}
+
+		for _, lib := range libs {
+			if lib.Format != formatPE {
+				continue
+			}
+			for _, w := range m.apply(lib, filepath.Base(g.Def)) {
+				log.Printf("warning: %s", w)
+			}
+		}
+	}
+
+	return libs, 0
+}
+
+// symbols returns the chosen exports of libs, and the names of all of them.
+func (g *generator) symbols(libs []*library) (symbols []mergedSymbol, exported map[string]bool, code int) {
+	if len(libs) == 1 {
+		for _, s := range libs[0].Symbols {
+			symbols = append(symbols, mergedSymbol{symbol: s})
+		}
+	} else {
+		symbols = mergeLibraries(libs)
+	}
+
+	exported = map[string]bool{}
+	for _, v := range symbols {
+		exported[v.Name] = true
+	}
+
+	if g.SkipForwarders {
+		var kept []mergedSymbol
+		for _, v := range symbols {
+			if v.Forwarder == "" {
+				kept = append(kept, v)
+			}
+		}
+		symbols = kept
+	}
+
+	allowlist := g.Symbols
+	if g.SymbolsFile != "" {
+		list, err := readSymbolList(g.SymbolsFile)
+		if err != nil {
+			log.Printf("unable to read the symbol list: %v\n", err)
+			return nil, nil, 66
+		}
+		allowlist = append(append([]string{}, allowlist...), list...)
+	}
+
+	filter, err := newSymbolFilter(g.Include, g.Exclude, allowlist)
+	if err != nil {
+		log.Printf("unable to parse the filters: %v\n", err)
+		return nil, nil, 64
+	}
+	symbols, err = filter.apply(symbols)
+	if err != nil {
+		log.Printf("unable to filter the exports: %v\n", err)
+		return nil, nil, 65
+	}
+
+	return symbols, exported, 0
+}
+
+// namer returns the naming rules.
+func (g *generator) namer() (*namer, error) {
+	n := &namer{
+		TrimPrefixes: g.TrimPrefix,
+		TrimSuffixes: g.TrimSuffix,
+		Overrides:    g.Rename,
+		Options: utils.IdentifierOptions{
+			CamelCase:       g.CamelCase,
+			Initialisms:     g.Initialisms,
+			KeepUnicode:     g.KeepUnicode,
+			KeepUnderscores: g.KeepUnderscores,
+		},
+	}
+	for _, rule := range g.Rewrite {
+		r, err := parseRewrite(rule)
+		if err != nil {
+			return nil, err
+		}
+		n.Rewrites = append(n.Rewrites, r)
+	}
+
+	return n, nil
+}
+
+// run generates the file, and returns the exit code.
+func (g *generator) run() int {
+	if len(g.DLLs) == 0 {
+		log.Printf("no DLL specified")
+		return 64
+	}
+	lazyVars := g.Style == styleLazyVars
+	if g.Style != "" && g.Style != styleStruct && !lazyVars {
+		log.Printf("unknown style \"%s\"", g.Style)
+		return 64
+	}
+	if lazyVars && len(g.DLLs) > 1 {
+		log.Printf("-style=%s does not support multiple DLLs", styleLazyVars)
+		return 64
+	}
+	names, err := g.namer()
+	if err != nil {
+		log.Printf("unable to parse the naming rules: %v\n", err)
+		return 64
+	}
+	tmpl := srcTemplate
+	if g.Template != "" {
+		text, err := os.ReadFile(g.Template)
+		if err != nil {
+			log.Printf("unable to read the template: %v\n", err)
+			return 66
+		}
+		tmpl, err = parseTemplate(filepath.Base(g.Template), string(text), names)
+		if err != nil {
+			log.Printf("unable to parse the template: %v\n", err)
+			return 65
+		}
+	}
+
+	// parse the exports
+	libs, code := g.openLibraries()
+	if code != 0 {
+		return code
+	}
+	// C types and #if conditions depend on the OS, and a struct for several DLLs has no build constraint
+	if g.Header != "" {
+		for _, lib := range libs[1:] {
+			if lib.GOOS != libs[0].GOOS {
+				log.Printf("-header cannot be used with DLLs for different OSes (%s and %s), as the C types depend on the OS", libs[0].GOOS, lib.GOOS)
+				return 64
+			}
+		}
+	}
+	g.outputDefaults(libs[0].fileName())
+
+	// parse the package metadata
+	packageName, err := getPackageName([]string{packageDirectory(g.Output)}, g.Tags)
+	if err != nil {
+		log.Printf("unable to parse package name: %v", err)
+		return 78
+	}
+	if packageName == "" {
+		log.Printf("Current package does not have a name yet. Maybe create at least one valid .go file and try again?")
+		return 78
+	}
+
+	d := templateData{
+		SelfImportPath:       importPath,
+		SelfPackageName:      selfPackageName,
+		SelfExecutableName:   selfExecutableName,
+		SelfDocumentationURL: documentationURL,
+		CommandLineRaw:       g.CommandLineRaw,
+		CommandLineCooked:    g.CommandLineCooked,
+
+		SelfGenerate: g.SelfGenerate,
+		LazyVars:     lazyVars,
+
+		DestinationPackageName: packageName,
+		TypeName:               g.Type,
+	}
+
+	procType := "*" + selfPackageName + ".Proc"
+	if g.Lazy || lazyVars {
+		procType = "*" + selfPackageName + ".LazyProc"
+	}
+	if lazyVars {
+		d.Imports = append(d.Imports, "errors", "sync")
+		d.NewLazyDLL = selfPackageName + ".NewLazyDLL"
+	} else {
+		d.Imports = append(d.Imports, importPath)
+	}
+	if len(libs) == 1 {
+		lib := libs[0]
+		d.DllFileName = lib.fileName()
+		d.BuildConstraint = lib.GOOS
+		if lib.Format == formatPE {
+			d.Imports = append(d.Imports, "golang.org/x/sys/windows")
+			procType = "*windows.Proc"
+			if g.Lazy || lazyVars {
+				procType = "*windows.LazyProc"
+			}
+			// bare names are only searched for in System32, like goinvoke.Unmarshal does
+			d.NewLazyDLL = "windows.NewLazySystemDLL"
+		} else if lazyVars {
+			d.Imports = append(d.Imports, importPath)
+		}
+	} else {
+		// one struct for all the OSes, without a build constraint
+		var names []string
+		for _, lib := range libs {
+			names = append(names, lib.fileName())
+		}
+		d.DllFileName = strings.Join(names, "\", \"")
+		procType = selfPackageName + ".FunctionPointer"
+		if g.Lazy {
+			log.Printf("warning: -lazy has no effect with multiple DLLs")
+		}
+	}
+
+	symbols, exported, code := g.symbols(libs)
+	if code != 0 {
+		return code
+	}
+
+	fields := map[string]string{}
+	used := map[string]bool{}
+	for _, v := range symbols {
+		if lazyVars && v.Name == "" {
+			log.Printf("warning: \"%s\" is skipped, since lazy procs cannot be found by ordinal", filterName(v.symbol))
+			continue
+		}
+		name, signature := names.fieldName(v.symbol)
+		fieldName := uniqueFieldName(name, used)
+		if fieldName != name && signature == "" {
+			// overloaded C++ functions are expected to collide
+			log.Printf("warning: \"%s\" is generated as \"%s\", since \"%s\" is already used", filterName(v.symbol), fieldName, name)
+		}
+		if v.Name != "" && !v.Data {
+			// no wrapper for variables
+			fields[v.Name] = fieldName
+		}
+		e := export{
+			Field:     fieldName,
+			Type:      procType,
+			Ordinal:   v.Ordinal,
+			Function:  v.Name,
+			Forwarder: v.Forwarder,
+			Kind:      kindFunction,
+			Demangled: signature,
+			GOOS:      strings.Join(v.GOOS, ","),
+			Comment:   exportComment(libs, v),
+			Doc:       signature,
+		}
+		if len(libs) == 1 && libs[0].Format == formatPE {
+			e.RVA = uint32(v.Address)
+		}
+		if v.Data {
+			e.Kind = kindData
+		}
+		d.Exports = append(d.Exports, e)
+	}
+
+	// parse the header
+	if g.Header != "" {
+		h, err := parseCHeaderFile(g.Header, libs[0].GOOS)
+		if err != nil {
+			log.Printf("unable to read the header: %v\n", err)
+			return 66
+		}
+
+		var imports []string
+		d.HeaderFileName = filepath.Base(g.Header)
+		d.Constants, d.Types, d.Wrappers, imports = bindHeader(h, libs[0].GOOS, names, exported, fields)
+		d.Imports = append(d.Imports, imports...)
+		if len(d.Wrappers) > 0 {
+			d.Imports = append(d.Imports, importPath, "syscall")
+		}
+	}
+	d.Imports = uniqueStrings(d.Imports)
+	d.ProcType = procType
+
+	// errors are the user's if the template is theirs
+	code = 70
+	if g.Template != "" {
+		code = 65
+	}
+
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, d)
+	if err != nil {
+		log.Printf("unable to fill the template: %v\n", err)
+		return code
+	}
+
+	// format the output
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Printf("unable to format the source code: %v\n", err)
+		return code
+	}
+
+	if g.Check {
+		existing, err := os.ReadFile(g.Output)
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("unable to read file \"%s\": %v\n", g.Output, err)
+			return 66
+		}
+
+		diff := unifiedDiff(g.Output, g.Output+" (generated)", string(existing), string(src))
+		if diff != "" {
+			fmt.Print(diff)
+			log.Printf("\"%s\" is not up to date\n", g.Output)
+			return 1
+		}
+		return 0
+	}
+
+	// flush to the destination file
+	// perm is before umask as per doc (https://pkg.go.dev/os#WriteFile) so we are safe to use 0666 here
+	err = os.WriteFile(g.Output, src, 0666)
+	if err != nil {
+		log.Printf("unable to write to file \"%s\": %v\n", g.Output, err)
+		return 73
+	}
+
+	return 0
+}
+
+// exportComment describes anything unusual about an export.
+func exportComment(libs []*library, s mergedSymbol) string {
+	var notes []string
+	if s.Data {
+		notes = append(notes, "data, not a function")
+	}
+	if s.Forwarder != "" {
+		notes = append(notes, "forwarded to "+s.Forwarder)
+	}
+	if missing := missingGOOS(libs, s.GOOS); len(s.GOOS) > 0 && len(missing) > 0 {
+		notes = append(notes, "not available on "+strings.Join(missing, ", "))
+	}
+
+	return strings.Join(notes, "; ")
+}
+
+// uniqueFieldName returns name, or name with a numeric suffix if it is already used. Different symbols might be mapped
+// to the same Go identifier, e.g. "foo_bar" and "foobar", or overloaded C++ functions.
+func uniqueFieldName(name string, used map[string]bool) string {
+	ret := name
+	for i := 2; used[ret]; i++ {
+		ret = name + strconv.Itoa(i)
+	}
+
+	used[ret] = true
+	return ret
+}
+
+// uniqueStrings returns s without duplicates, in the order of first appearance.
+func uniqueStrings(s []string) (ret []string) {
+	seen := map[string]bool{}
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// packageDirectory returns the package pattern of the directory containing the file at path.
+func packageDirectory(path string) string {
+	dir := filepath.Dir(path)
+	if filepath.IsAbs(dir) || dir == "." || strings.HasPrefix(dir, "."+string(filepath.Separator)) || strings.HasPrefix(dir, "..") {
+		return dir
+	}
+	return "." + string(filepath.Separator) + dir
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"go/format"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUniqueFieldName(t *testing.T) {
+	used := map[string]bool{}
+	assert.EqualValues(t, "Gzgetc", uniqueFieldName("Gzgetc", used))
+	assert.EqualValues(t, "Gzgetc2", uniqueFieldName("Gzgetc", used))
+	assert.EqualValues(t, "Gzgetc3", uniqueFieldName("Gzgetc", used))
+
+	used = map[string]bool{"Foo2": true}
+	assert.EqualValues(t, "Foo", uniqueFieldName("Foo", used))
+	assert.EqualValues(t, "Foo3", uniqueFieldName("Foo", used))
+}
+
+func TestExportComment(t *testing.T) {
+	libs := []*library{{GOOS: "windows"}, {GOOS: "linux"}}
+
+	assert.EqualValues(t, "", exportComment(libs, mergedSymbol{symbol: symbol{Name: "foo"}}))
+	assert.EqualValues(t, "data, not a function; not available on linux", exportComment(libs, mergedSymbol{
+		symbol: symbol{Name: "foo", Data: true},
+		GOOS:   []string{"windows"},
+	}))
+	assert.EqualValues(t, "forwarded to NTDLL.RtlAllocateHeap", exportComment(libs, mergedSymbol{
+		symbol: symbol{Name: "HeapAlloc", Forwarder: "NTDLL.RtlAllocateHeap"},
+		GOOS:   []string{"windows", "linux"},
+	}))
+}
+
+func TestLazyVarsTemplate(t *testing.T) {
+	d := templateData{
+		SelfPackageName:        selfPackageName,
+		DestinationPackageName: "foo",
+		TypeName:               "Foo",
+		DllFileName:            "foo.dll",
+		Imports:                []string{"errors", "sync", "golang.org/x/sys/windows"},
+		LazyVars:               true,
+		NewLazyDLL:             "windows.NewLazySystemDLL",
+		ProcType:               "*windows.LazyProc",
+		Exports:                []export{{Field: "FooInit", Function: "foo_init"}},
+	}
+
+	var b bytes.Buffer
+	assert.NoError(t, srcTemplate.Execute(&b, d))
+	src, err := format.Source(b.Bytes())
+	assert.NoError(t, err)
+	assert.Contains(t, string(src), `modFoo = windows.NewLazySystemDLL("foo.dll")`)
+	assert.Contains(t, string(src), `procFooInit = modFoo.NewProc("foo_init")`)
+	assert.Contains(t, string(src), "func LoadFoo() error {")
+	assert.NotContains(t, string(src), "Unmarshal")
+}
+
+func TestWrapperTemplate(t *testing.T) {
+	d := templateData{
+		SelfPackageName:        selfPackageName,
+		DestinationPackageName: "foo",
+		TypeName:               "Foo",
+		DllFileName:            "libfoo.so",
+		Imports:                []string{importPath, "syscall"},
+		ProcType:               "*goinvoke.Proc",
+		Exports:                []export{{Field: "FooOpen", Function: "foo_open"}, {Field: "FooClose", Function: "foo_close"}},
+		HeaderFileName:         "foo.h",
+		Wrappers: []wrapper{
+			{Method: "CallFooOpen", Field: "FooOpen", Function: "foo_open", Params: "path string", Args: []string{"path"}, Result: "int32"},
+			{Method: "CallFooClose", Field: "FooClose", Function: "foo_close"},
+		},
+	}
+
+	var b bytes.Buffer
+	assert.NoError(t, srcTemplate.Execute(&b, d))
+	src, err := format.Source(b.Bytes())
+	assert.NoError(t, err)
+
+	// errno is not an error: it is left over by successful calls, too
+	assert.Contains(t, string(src), "func (dll *Foo) CallFooOpen(path string) (result int32, errno syscall.Errno) {")
+	assert.Contains(t, string(src), "result, err := goinvoke.Call1[int32](dll.FooOpen, path)")
+	assert.Contains(t, string(src), "func (dll *Foo) CallFooClose() (errno syscall.Errno) {")
+	assert.Contains(t, string(src), "_, err := goinvoke.Call1[struct{}](dll.FooClose)")
+}
+
+func TestUserTemplate(t *testing.T) {
+	text, err := os.ReadFile("testdata/interface.tmpl")
+	assert.NoError(t, err)
+	tmpl, err := parseTemplate("interface.tmpl", string(text), &namer{})
+	assert.NoError(t, err)
+
+	d := templateData{
+		SelfImportPath:         importPath,
+		SelfExecutableName:     selfExecutableName,
+		DestinationPackageName: "foo",
+		TypeName:               "Foo",
+		DllFileName:            "foo.dll",
+		Imports:                []string{importPath, "golang.org/x/sys/windows"},
+		Exports: []export{
+			{Field: "BarGetID", Type: "*windows.Proc", Function: "?get_id@Bar@@QEAAHXZ", Kind: kindFunction, Demangled: "Bar::get_id()", RVA: 0x1010},
+			{Field: "FooVersion", Type: "*windows.Proc", Function: "foo_version", Kind: kindData},
+			{Field: "Ord2", Type: "*windows.Proc", Ordinal: 2, Kind: kindFunction},
+		},
+	}
+
+	var b bytes.Buffer
+	assert.NoError(t, tmpl.Execute(&b, d))
+	src, err := format.Source(b.Bytes())
+	assert.NoError(t, err)
+	assert.Contains(t, string(src), "BarGetID(args ...uintptr) (uintptr, error) // Bar::get_id()")
+	assert.Contains(t, string(src), "barGetID   *windows.Proc `func:\"?get_id@Bar@@QEAAHXZ\"` // RVA 0x1010")
+	assert.Contains(t, string(src), "ord2       *windows.Proc `ordinal:\"2\"`")
+	assert.Contains(t, string(src), "func (dll *foo) Ord2(args ...uintptr) (uintptr, error) {")
+	assert.NotContains(t, string(src), "FooVersion(")
+	assert.NotContains(t, string(src), importPath)
+
+	_, err = parseTemplate("broken.tmpl", "{{ .Exports", &namer{})
+	assert.Error(t, err)
+}
+
+func TestHeaderWithSeveralOSes(t *testing.T) {
+	g := &generator{
+		DLLs:   []string{filepath.Join("testdata", "foo.lib"), filepath.Join("testdata", "libfoo.dylib")},
+		Header: filepath.Join(t.TempDir(), "foo.h"),
+		Output: filepath.Join(t.TempDir(), "foo.go"),
+	}
+	assert.EqualValues(t, 64, g.run())
+	assert.NoFileExists(t, g.Output)
+}
+
+func TestUnexported(t *testing.T) {
+	assert.EqualValues(t, "foo", unexported("Foo"))
+	assert.EqualValues(t, "id", unexported("ID"))
+	assert.EqualValues(t, "httpServer", unexported("HTTPServer"))
+	assert.EqualValues(t, "urlToUTF8", unexported("URLToUTF8"))
+	assert.EqualValues(t, "x", unexported("X"))
+	assert.EqualValues(t, "ids", unexported("IDs"))
+	assert.EqualValues(t, "dlls", unexported("DLLs"))
+	assert.EqualValues(t, "dllsLoaded", unexported("DLLsLoaded"))
+	assert.EqualValues(t, "urlSet", unexported("URLSet"))
+	assert.EqualValues(t, "osSupport", unexported("OSSupport"))
+}
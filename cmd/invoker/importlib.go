@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// import types and name types of short import objects
+const (
+	importCode  = 0
+	importData  = 1
+	importConst = 2
+
+	importNameOrdinal    = 0 // imported by ordinal; the name is only for the linker
+	importName           = 1 // the export name is the symbol name
+	importNameNoPrefix   = 2 // the symbol name without its leading "?", "@" or "_"
+	importNameUndecorate = 3 // the same, truncated at the first "@"
+	importNameExportAs   = 4 // the export name follows the DLL name
+)
+
+// arMagic starts an archive, e.g. an import library.
+const arMagic = "!<arch>\n"
+
+// readImportLibrary reads the exports of the DLL an import library links to. Both the short import objects of MSVC and
+// LLVM, and the long ones of GNU dlltool are understood; other archive members are ignored.
+func readImportLibrary(path string) (*library, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := archiveMembers(data)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &library{
+		Path:   path,
+		Format: formatPE,
+		GOOS:   "windows",
+	}
+	dllNames := map[string]bool{}
+	seen := map[symbol]bool{}
+	for _, member := range members {
+		s, dllName, ok := shortImport(member)
+		if !ok {
+			s, dllName, ok = longImport(member)
+		}
+		if dllName != "" {
+			dllNames[strings.ToLower(dllName)] = true
+			if l.FileName == "" {
+				l.FileName = dllName
+			}
+		}
+		if !ok || seen[s] {
+			continue
+		}
+		seen[s] = true
+		l.Symbols = append(l.Symbols, s)
+	}
+
+	if len(dllNames) == 0 {
+		return nil, errors.New("not an import library")
+	}
+	if len(dllNames) > 1 {
+		var names []string
+		for name := range dllNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("the import library links to several DLLs: %s", strings.Join(names, ", "))
+	}
+
+	sort.Slice(l.Symbols, func(i, j int) bool {
+		a, b := l.Symbols[i], l.Symbols[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Ordinal < b.Ordinal
+	})
+	return l, nil
+}
+
+// archiveMembers returns the contents of the members of an ar archive, except the symbol tables and the long names.
+func archiveMembers(data []byte) (ret [][]byte, err error) {
+	if !bytes.HasPrefix(data, []byte(arMagic)) {
+		return nil, errors.New("not an archive")
+	}
+
+	for pos := len(arMagic); pos < len(data); {
+		if len(data)-pos < 60 || string(data[pos+58:pos+60]) != "`\n" {
+			return nil, fmt.Errorf("invalid archive member header at offset %d", pos)
+		}
+		name := strings.TrimSpace(string(data[pos : pos+16]))
+		size, err := strconv.ParseUint(strings.TrimSpace(string(data[pos+48:pos+58])), 10, 63)
+		if err != nil || size > uint64(len(data)-pos-60) {
+			return nil, fmt.Errorf("invalid archive member size at offset %d", pos)
+		}
+
+		pos += 60
+		if name != "/" && name != "//" {
+			ret = append(ret, data[pos:pos+int(size)])
+		}
+		// members are 2-byte aligned
+		pos += int(size) + int(size%2)
+	}
+
+	return ret, nil
+}
+
+// shortImport parses a short import object, which describes one export.
+func shortImport(member []byte) (s symbol, dllName string, ok bool) {
+	if len(member) < 20 || binary.LittleEndian.Uint16(member[0:]) != 0 || binary.LittleEndian.Uint16(member[2:]) != 0xffff {
+		return symbol{}, "", false
+	}
+	hint := binary.LittleEndian.Uint16(member[16:])
+	flags := binary.LittleEndian.Uint16(member[18:])
+
+	strs := strings.Split(string(member[20:]), "\x00")
+	if len(strs) < 2 {
+		return symbol{}, "", false
+	}
+	name, dllName := strs[0], strs[1]
+
+	s.Data = flags&3 == importData || flags&3 == importConst
+	switch flags >> 2 & 7 {
+	case importNameOrdinal:
+		s.Ordinal, s.Alias = uint32(hint), name
+	case importName:
+		s.Name = name
+	case importNameNoPrefix, importNameUndecorate:
+		s.Name = name
+		if len(s.Name) > 0 && strings.IndexByte("?@_", s.Name[0]) >= 0 {
+			s.Name = s.Name[1:]
+		}
+		if flags>>2&7 == importNameUndecorate {
+			s.Name, _, _ = strings.Cut(s.Name, "@")
+		}
+	case importNameExportAs:
+		if len(strs) < 3 {
+			return symbol{}, "", false
+		}
+		s.Name = strs[2]
+	default:
+		return symbol{}, "", false
+	}
+
+	return s, dllName, true
+}
+
+// longImport parses a COFF object created by GNU dlltool. Each export has its own object, with the name in the
+// .idata$6 section, or the ordinal in the .idata$5 section. The DLL name is in the .idata$7 section of another one.
+func longImport(member []byte) (s symbol, dllName string, ok bool) {
+	f, err := pe.NewFile(bytes.NewReader(member))
+	if err != nil {
+		return symbol{}, "", false
+	}
+	defer f.Close()
+
+	var imp string
+	for _, sym := range f.Symbols {
+		if strings.HasPrefix(sym.Name, "__imp_") && sym.SectionNumber > 0 {
+			imp = strings.TrimPrefix(sym.Name, "__imp_")
+			if f.Machine == pe.IMAGE_FILE_MACHINE_I386 {
+				imp = strings.TrimPrefix(imp, "_")
+			}
+			break
+		}
+	}
+
+	if imp == "" {
+		// the tail object names the DLL
+		if sect := f.Section(".idata$7"); sect != nil {
+			data, err := sect.Data()
+			if err == nil && len(sect.Relocs) == 0 {
+				dllName, _, _ = strings.Cut(string(data), "\x00")
+			}
+		}
+		return symbol{}, dllName, false
+	}
+
+	if sect := f.Section(".idata$6"); sect != nil {
+		data, err := sect.Data()
+		if err != nil || len(data) < 3 {
+			return symbol{}, "", false
+		}
+		// the hint comes first
+		s.Name, _, _ = strings.Cut(string(data[2:]), "\x00")
+	} else if sect := f.Section(".idata$5"); sect != nil {
+		data, err := sect.Data()
+		if err != nil || len(data) < 4 {
+			return symbol{}, "", false
+		}
+		entry := uint64(binary.LittleEndian.Uint32(data))
+		flag := uint64(1) << 31
+		if len(data) >= 8 && f.Machine != pe.IMAGE_FILE_MACHINE_I386 {
+			entry, flag = binary.LittleEndian.Uint64(data), uint64(1)<<63
+		}
+		if entry&flag == 0 {
+			return symbol{}, "", false
+		}
+		s.Ordinal, s.Alias = uint32(entry&0xffff), imp
+	} else {
+		return symbol{}, "", false
+	}
+
+	// functions have a jump thunk
+	text := f.Section(".text")
+	s.Data = text == nil || text.Size == 0
+	return s, "", true
+}
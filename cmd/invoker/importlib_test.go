@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// fixtures are generated from testdata/foo.def
+func TestReadImportLibrary(t *testing.T) {
+	l, err := openLibrary("testdata/foo.lib")
+	assert.NoError(t, err)
+	assert.EqualValues(t, formatPE, l.Format)
+	assert.EqualValues(t, "foo.dll", l.fileName())
+	assert.EqualValues(t, []symbol{
+		{Ordinal: 2, Alias: "foo_hidden"},
+		{Name: "foo_alias"},
+		{Name: "foo_init"},
+		{Name: "foo_stdcall@8"},
+		{Name: "foo_version", Data: true},
+	}, l.Symbols)
+
+	// decorated names
+	l, err = openLibrary("testdata/foo_x86.lib")
+	assert.NoError(t, err)
+	assert.Contains(t, l.Symbols, symbol{Name: "foo_stdcall"})
+}
+
+func TestShortImport(t *testing.T) {
+	member := func(flags uint16, strs string) []byte {
+		b := make([]byte, 20)
+		binary.LittleEndian.PutUint16(b[2:], 0xffff)
+		binary.LittleEndian.PutUint16(b[6:], pe.IMAGE_FILE_MACHINE_I386)
+		binary.LittleEndian.PutUint16(b[16:], 7)
+		binary.LittleEndian.PutUint16(b[18:], flags)
+		return append(b, strs...)
+	}
+
+	s, dllName, ok := shortImport(member(importNameUndecorate<<2, "_foo@4\x00foo.dll\x00"))
+	assert.True(t, ok)
+	assert.EqualValues(t, "foo.dll", dllName)
+	assert.EqualValues(t, symbol{Name: "foo"}, s)
+
+	s, _, ok = shortImport(member(importNameExportAs<<2|importConst, "_foo\x00foo.dll\x00bar\x00"))
+	assert.True(t, ok)
+	assert.EqualValues(t, symbol{Name: "bar", Data: true}, s)
+
+	s, _, ok = shortImport(member(importNameOrdinal<<2, "foo\x00foo.dll\x00"))
+	assert.True(t, ok)
+	assert.EqualValues(t, symbol{Ordinal: 7, Alias: "foo"}, s)
+
+	_, _, ok = shortImport([]byte("not an import"))
+	assert.False(t, ok)
+}
+
+// coffObject builds an x86-64 COFF object with the sections, and one symbol per section.
+func coffObject(sections map[string][]byte, symbols map[string]string) []byte {
+	var names []string
+	for name := range sections {
+		names = append(names, name)
+	}
+
+	var b bytes.Buffer
+	header := pe.FileHeader{Machine: pe.IMAGE_FILE_MACHINE_AMD64, NumberOfSections: uint16(len(names))}
+	dataOffset := 20 + 40*len(names)
+	var data []byte
+	var headers []pe.SectionHeader32
+	for _, name := range names {
+		h := pe.SectionHeader32{SizeOfRawData: uint32(len(sections[name])), PointerToRawData: uint32(dataOffset + len(data))}
+		copy(h.Name[:], name)
+		headers = append(headers, h)
+		data = append(data, sections[name]...)
+	}
+	header.PointerToSymbolTable = uint32(dataOffset + len(data))
+
+	var syms []pe.COFFSymbol
+	var strtab []byte
+	for name, section := range symbols {
+		s := pe.COFFSymbol{StorageClass: 2}
+		for i, n := range names {
+			if n == section {
+				s.SectionNumber = int16(i + 1)
+			}
+		}
+		// long names are in the string table
+		binary.LittleEndian.PutUint32(s.Name[4:], uint32(4+len(strtab)))
+		strtab = append(strtab, name+"\x00"...)
+		syms = append(syms, s)
+	}
+	header.NumberOfSymbols = uint32(len(syms))
+
+	_ = binary.Write(&b, binary.LittleEndian, header)
+	_ = binary.Write(&b, binary.LittleEndian, headers)
+	b.Write(data)
+	_ = binary.Write(&b, binary.LittleEndian, syms)
+	_ = binary.Write(&b, binary.LittleEndian, uint32(4+len(strtab)))
+	b.Write(strtab)
+	return b.Bytes()
+}
+
+func TestLongImport(t *testing.T) {
+	s, _, ok := longImport(coffObject(map[string][]byte{
+		".text":    {0xff, 0x25, 0, 0, 0, 0},
+		".idata$5": make([]byte, 8),
+		".idata$6": []byte("\x01\x00foo_init\x00\x00"),
+	}, map[string]string{"__imp_foo_init": ".idata$5", "foo_init": ".text"}))
+	assert.True(t, ok)
+	assert.EqualValues(t, symbol{Name: "foo_init"}, s)
+
+	// by ordinal, without a thunk
+	s, _, ok = longImport(coffObject(map[string][]byte{
+		".idata$5": {2, 0, 0, 0, 0, 0, 0, 0x80},
+	}, map[string]string{"__imp_foo_hidden": ".idata$5"}))
+	assert.True(t, ok)
+	assert.EqualValues(t, symbol{Ordinal: 2, Alias: "foo_hidden", Data: true}, s)
+
+	_, dllName, ok := longImport(coffObject(map[string][]byte{
+		".idata$7": []byte("foo.dll\x00"),
+	}, map[string]string{"__libfoo_a_iname": ".idata$7"}))
+	assert.False(t, ok)
+	assert.EqualValues(t, "foo.dll", dllName)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/jamesits/goinvoke/utils"
+	"os"
+)
+
+// ldCachePath is the cache of the ld.so(8) search path, as written by ldconfig(8).
+const ldCachePath = "/etc/ld.so.cache"
+
+const (
+	ldCacheOldMagic = "ld.so-1.7.0"
+	ldCacheNewMagic = "glibc-ld.so.cache1.1"
+
+	ldCacheOldHeaderSize = 16 // magic, padded to 12 bytes, and nlibs
+	ldCacheOldEntrySize  = 12 // flags, key and value
+	ldCacheNewHeaderSize = 48
+	ldCacheNewEntrySize  = 24 // flags, key, value, osversion and hwcap
+)
+
+// parseLdCache parses an ld.so.cache in the glibc format, either alone or after an old (libc5) one, and returns the
+// paths of each soname, in the order ld.so tries them. Entries for all the architectures are included.
+func parseLdCache(data []byte) (map[string][]string, error) {
+	// the new format might follow the old one, 8-byte aligned
+	if bytes.HasPrefix(data, []byte(ldCacheOldMagic)) {
+		if len(data) < ldCacheOldHeaderSize {
+			return nil, errors.New("truncated ld.so.cache")
+		}
+		n := int(utils.HostByteOrder.Uint32(data[12:]))
+		offset := ldCacheOldHeaderSize + n*ldCacheOldEntrySize
+		offset = (offset + 7) &^ 7
+		if offset > len(data) {
+			return nil, errors.New("truncated ld.so.cache")
+		}
+		data = data[offset:]
+	}
+	if !bytes.HasPrefix(data, []byte(ldCacheNewMagic)) || len(data) < ldCacheNewHeaderSize {
+		return nil, errors.New("not an ld.so.cache in the glibc format")
+	}
+
+	// string offsets are relative to the header of the new format
+	str := func(offset uint32) (string, bool) {
+		if int(offset) >= len(data) {
+			return "", false
+		}
+		end := bytes.IndexByte(data[offset:], 0)
+		if end < 0 {
+			return "", false
+		}
+		return string(data[offset : int(offset)+end]), true
+	}
+
+	n := int(utils.HostByteOrder.Uint32(data[20:]))
+	if n > (len(data)-ldCacheNewHeaderSize)/ldCacheNewEntrySize {
+		return nil, errors.New("truncated ld.so.cache")
+	}
+	ret := map[string][]string{}
+	for i := 0; i < n; i++ {
+		entry := data[ldCacheNewHeaderSize+i*ldCacheNewEntrySize:]
+		key, ok1 := str(utils.HostByteOrder.Uint32(entry[4:]))
+		value, ok2 := str(utils.HostByteOrder.Uint32(entry[8:]))
+		if !ok1 || !ok2 {
+			return nil, errors.New("invalid string offset in ld.so.cache")
+		}
+		ret[key] = append(ret[key], value)
+	}
+
+	return ret, nil
+}
+
+// readLdCache reads the ld.so.cache of this system. A missing cache is empty.
+func readLdCache() (map[string][]string, error) {
+	data, err := os.ReadFile(ldCachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLdCache(data)
+}
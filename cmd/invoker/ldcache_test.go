@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/jamesits/goinvoke/utils"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+// ldCache builds an ld.so.cache in the glibc format, optionally after an empty libc5 one.
+func ldCache(old bool, entries [][2]string) []byte {
+	u32 := func(b []byte, v uint32) []byte {
+		var buf [4]byte
+		utils.HostByteOrder.PutUint32(buf[:], v)
+		return append(b, buf[:]...)
+	}
+
+	var b []byte
+	if old {
+		b = append([]byte(ldCacheOldMagic), 0)
+		b = u32(b, 0)
+	}
+
+	strings := ldCacheNewHeaderSize + len(entries)*ldCacheNewEntrySize
+	var strtab []byte
+	var table []byte
+	for _, e := range entries {
+		table = u32(table, 0x0303)
+		table = u32(table, uint32(strings+len(strtab)))
+		strtab = append(strtab, e[0]+"\x00"...)
+		table = u32(table, uint32(strings+len(strtab)))
+		strtab = append(strtab, e[1]+"\x00"...)
+		table = append(table, make([]byte, 12)...)
+	}
+
+	b = append(b, ldCacheNewMagic...)
+	b = u32(b, uint32(len(entries)))
+	b = u32(b, uint32(len(strtab)))
+	b = append(b, make([]byte, 20)...)
+	b = append(b, table...)
+	return append(b, strtab...)
+}
+
+func TestParseLdCache(t *testing.T) {
+	entries := [][2]string{
+		{"libz.so.1", "/lib/x86_64-linux-gnu/libz.so.1"},
+		{"libz.so.1", "/lib/i386-linux-gnu/libz.so.1"},
+		{"libc.so.6", "/lib/x86_64-linux-gnu/libc.so.6"},
+	}
+	expected := map[string][]string{
+		"libz.so.1": {"/lib/x86_64-linux-gnu/libz.so.1", "/lib/i386-linux-gnu/libz.so.1"},
+		"libc.so.6": {"/lib/x86_64-linux-gnu/libc.so.6"},
+	}
+
+	for _, old := range []bool{false, true} {
+		cache, err := parseLdCache(ldCache(old, entries))
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, cache)
+	}
+
+	_, err := parseLdCache([]byte("not a cache"))
+	assert.Error(t, err)
+	_, err = parseLdCache(ldCache(false, entries)[:ldCacheNewHeaderSize+10])
+	assert.Error(t, err)
+}
+
+func TestReadLdCache(t *testing.T) {
+	if _, err := os.Stat(ldCachePath); err != nil {
+		t.Skip("no ld.so.cache on this system")
+	}
+
+	cache, err := readLdCache()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cache["libc.so.6"])
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// binary formats understood by the generator
+const (
+	formatPE    = "PE"
+	formatELF   = "ELF"
+	formatMachO = "Mach-O"
+
+	formatArchive = "archive" // e.g. an import library, whose exports are read as formatPE
+)
+
+// symbol is a function or a variable exported by a library.
+type symbol struct {
+	Name      string
+	Ordinal   uint32 // PE only
+	Address   uint64 // the RVA for PE, the symbol value otherwise; 0 if unknown, e.g. for forwarders or in import libraries
+	Forwarder string // PE only, e.g. "NTDLL.RtlAllocateHeap"
+	Version   string // ELF only
+	Data      bool   // a variable rather than a function
+	Alias     string // ordinal-only exports: the name from a module-definition file
+}
+
+// library contains everything the generator needs to know about a binary.
+type library struct {
+	Path     string
+	FileName string // the DLL to load, if it is not the file at Path, e.g. for import libraries
+	Format   string
+	GOOS     string // the OS this library is built for, in runtime.GOOS terms
+	Symbols  []symbol
+}
+
+// fileName returns the file name to load the library by.
+func (l *library) fileName() string {
+	if l.FileName != "" {
+		return l.FileName
+	}
+	return filepath.Base(l.Path)
+}
+
+// openLibrary detects the format of the binary at path and reads its exports.
+func openLibrary(path string) (*library, error) {
+	format, err := detectFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case formatPE:
+		return readPE(path)
+	case formatELF:
+		return readELF(path)
+	case formatMachO:
+		return readMachO(path)
+	default:
+		return readImportLibrary(path)
+	}
+}
+
+// detectFormat detects the format of the binary at path by its magic number.
+func detectFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	magic := make([]byte, len(arMagic))
+	n, err := io.ReadFull(f, magic)
+	_ = f.Close()
+	if n < 4 {
+		return "", err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("MZ")):
+		return formatPE, nil
+	case bytes.HasPrefix(magic, []byte("\x7fELF")):
+		return formatELF, nil
+	case bytes.HasPrefix(magic, []byte{0xcf, 0xfa, 0xed, 0xfe}), // MH_MAGIC_64
+		bytes.HasPrefix(magic, []byte{0xce, 0xfa, 0xed, 0xfe}), // MH_MAGIC
+		bytes.HasPrefix(magic, []byte{0xca, 0xfe, 0xba, 0xbe}): // FAT_MAGIC
+		return formatMachO, nil
+	case bytes.Equal(magic, []byte(arMagic)):
+		return formatArchive, nil
+	default:
+		return "", errors.New("unknown binary format")
+	}
+}
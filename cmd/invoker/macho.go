@@ -0,0 +1,100 @@
+package main
+
+import (
+	"debug/macho"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Mach-O nlist n_type bits, see <mach-o/nlist.h>
+const (
+	machoNStab = 0xe0
+	machoNPExt = 0x10
+	machoNType = 0x0e
+	machoNExt  = 0x01
+	machoNSect = 0x0e
+)
+
+// Mach-O section attributes, see <mach-o/loader.h>
+const (
+	machoSAttrPureInstructions = 0x80000000
+	machoSAttrSomeInstructions = 0x00000400
+)
+
+// readMachO reads the exported functions of a Mach-O dylib, or a fat (universal) dylib.
+// For fat files, the exports from all the architectures are merged.
+func readMachO(path string) (*library, error) {
+	var files []*macho.File
+
+	fat, err := macho.OpenFat(path)
+	if err == nil {
+		defer fat.Close()
+		for _, arch := range fat.Arches {
+			files = append(files, arch.File)
+		}
+	} else if errors.Is(err, macho.ErrNotFat) {
+		f, err := macho.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		files = append(files, f)
+	} else {
+		return nil, err
+	}
+
+	l := &library{
+		Path:   path,
+		Format: formatMachO,
+		GOOS:   "darwin",
+	}
+
+	count := map[string]int{}
+	for _, f := range files {
+		if f.Symtab == nil {
+			continue
+		}
+
+		for _, s := range f.Symtab.Syms {
+			exported, data := isMachOExportedSymbol(f, s)
+			if !exported {
+				continue
+			}
+
+			// C symbols are prefixed with an underscore, which is not used with dlsym(3)
+			name := strings.TrimPrefix(s.Name, "_")
+			// the address is the one of the first architecture
+			if count[name] == 0 {
+				l.Symbols = append(l.Symbols, symbol{Name: name, Address: s.Value, Data: data})
+			}
+			count[name]++
+		}
+	}
+
+	sort.Slice(l.Symbols, func(i, j int) bool {
+		return l.Symbols[i].Name < l.Symbols[j].Name
+	})
+
+	for _, s := range l.Symbols {
+		if count[s.Name] < len(files) {
+			log.Printf("warning: \"%s\" is not exported by all the architectures", s.Name)
+		}
+	}
+
+	return l, nil
+}
+
+// isMachOExportedSymbol tests if s is a function or a variable defined in f, and visible to others.
+func isMachOExportedSymbol(f *macho.File, s macho.Symbol) (exported bool, data bool) {
+	if s.Type&machoNStab != 0 || s.Type&machoNType != machoNSect || s.Type&machoNExt == 0 || s.Type&machoNPExt != 0 {
+		return false, false
+	}
+
+	if s.Sect == 0 || int(s.Sect) > len(f.Sections) {
+		return false, false
+	}
+
+	return true, f.Sections[s.Sect-1].Flags&(machoSAttrPureInstructions|machoSAttrSomeInstructions) == 0
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// fixtures are generated by testdata/mkmacho.go
+func TestReadMachO(t *testing.T) {
+	l, err := openLibrary("testdata/libfoo.dylib")
+	assert.NoError(t, err)
+	assert.EqualValues(t, formatMachO, l.Format)
+	assert.EqualValues(t, "darwin", l.GOOS)
+	assert.EqualValues(t, []symbol{
+		{Name: "foo_bar", Address: 0x8},
+		{Name: "foo_init", Address: 0xc},
+		{Name: "foo_version", Address: 0x1000, Data: true},
+	}, l.Symbols)
+}
+
+func TestReadMachOFat(t *testing.T) {
+	l, err := openLibrary("testdata/libfoo_fat.dylib")
+	assert.NoError(t, err)
+	assert.EqualValues(t, formatMachO, l.Format)
+	assert.EqualValues(t, []symbol{
+		{Name: "foo_bar", Address: 0x8},
+		{Name: "foo_init", Address: 0xc},
+		{Name: "foo_neon"},
+		{Name: "foo_version", Address: 0x1000, Data: true},
+	}, l.Symbols)
+}
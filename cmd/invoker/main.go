@@ -1,15 +1,14 @@
 package main
 
 import (
-	"bytes"
 	"flag"
 	"fmt"
 	"github.com/jamesits/goinvoke/utils"
-	"github.com/saferwall/pe"
-	"go/format"
+	"go/token"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 )
@@ -21,138 +20,203 @@ const documentationURL = "https://pkg.go.dev/github.com/jamesits/goinvoke"
 
 // note: exit code conforms to sysexits.h
 func main() {
-	var err error
+	// subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			os.Exit(diffMain(os.Args[2:]))
+		case "exports":
+			os.Exit(exportsMain(os.Args[2:]))
+		case "call":
+			os.Exit(callMain(os.Args[2:]))
+		case "deps":
+			os.Exit(depsMain(os.Args[2:]))
+		case "doctor":
+			os.Exit(doctorMain(os.Args[2:]))
+		}
+	}
+
+	flag.Parse()
 
 	// check and mitigate arguments
-	if len(dllPath) == 0 {
+	if (len(dllPaths) == 0) == (configFileName == "") {
 		flag.Usage()
 		os.Exit(64)
 	}
-	if utils.IsImplicitRelativePath(dllPath) {
-		// mimic the behavior roughly where LoadLibrary() is called with LOAD_LIBRARY_SEARCH_SYSTEM32 flag set
-
-		system32, err := utils.GetSystemDirectory()
-		if err != nil {
-			log.Printf("unable to get System32 directory: %v", err)
-			os.Exit(72)
-		}
 
-		dllPath = filepath.Join(system32, dllPath)
+	artificialArgv0 := selfExecutableName
+	if preserveRealArg0 {
+		artificialArgv0 = os.Args[0]
 	}
 
-	s, err := os.Stat(dllPath)
-	if err != nil {
-		log.Printf("\"%s\" not found: %v", s, err)
-		os.Exit(66)
-	}
-	if !s.Mode().IsRegular() {
-		log.Printf("\"%s\" is not a file", s)
-		os.Exit(66)
+	// -check must not change the output
+	commandLineRaw := []string{artificialArgv0}
+	for _, arg := range os.Args[1:] {
+		if !isCheckFlag(arg) {
+			commandLineRaw = append(commandLineRaw, arg)
+		}
 	}
 
-	if len(outputType) == 0 {
-		outputType = utils.FormatPublicType(utils.BaseName(dllPath))
+	if configFileName != "" {
+		os.Exit(configMain(configFileName, artificialArgv0))
 	}
 
-	if len(outputFileName) == 0 {
-		outputFileName = filepath.Join(".", strings.ToLower(utils.BaseName(dllPath))+"_dll.go")
+	var rename map[string]string
+	for _, r := range renames {
+		cName, goName, ok := strings.Cut(r, "=")
+		if !ok || cName == "" || !token.IsIdentifier(goName) {
+			log.Printf("invalid -rename \"%s\", expecting cname=GoName", r)
+			os.Exit(64)
+		}
+		if rename == nil {
+			rename = map[string]string{}
+		}
+		rename[cName] = goName
+	}
+
+	g := &generator{
+		DLLs:        dllPaths,
+		Type:        outputType,
+		Output:      outputFileName,
+		TrimPrefix:  trimPrefixes,
+		TrimSuffix:  trimSuffixes,
+		Rewrite:     rewriteRules,
+		Rename:      rename,
+		Tags:        strings.Split(buildTags, ","),
+		Lazy:        lazy,
+		Style:       style,
+		Header:      headerFileName,
+		Include:     includePatterns,
+		Exclude:     excludePatterns,
+		SymbolsFile: symbolsFileName,
+		Def:         defFileName,
+		Template:    templateFileName,
+		Check:       check,
+
+		SkipForwarders:  skipForwarders,
+		CamelCase:       camelCase,
+		Initialisms:     initialisms,
+		KeepUnicode:     keepUnicode,
+		KeepUnderscores: keepUnderscores,
+
+		CommandLineRaw:    commandLineRaw,
+		CommandLineCooked: quoteArgs(commandLineRaw),
+		SelfGenerate:      selfGenerate,
+	}
+
+	if dumpConfigFileName != "" {
+		l, code := g.manifest(dumpConfigFileName)
+		if code != 0 {
+			os.Exit(code)
+		}
+
+		err := writeManifest(dumpConfigFileName, &manifest{Libraries: []manifestLibrary{*l}})
+		if err != nil {
+			log.Printf("unable to write the manifest: %v\n", err)
+			os.Exit(73)
+		}
+		return
 	}
 
-	tags := strings.Split(buildTags, ",")
+	os.Exit(g.run())
+}
 
-	// parse the package metadata
-	packageName, err := getPackageName([]string{"."}, tags)
+// configMain generates all the libraries in a manifest, and returns the exit code.
+func configMain(path string, argv0 string) int {
+	m, err := readManifest(path)
 	if err != nil {
-		log.Printf("unable to parse package name: %v", err)
-		os.Exit(78)
-	}
-	if packageName == "" {
-		log.Printf("Current package does not have a name yet. Maybe create at least one valid .go file and try again?")
-		os.Exit(78)
+		log.Printf("unable to read the manifest: %v\n", err)
+		return 66
 	}
 
-	artificialArgv0 := selfExecutableName
-	if preserveRealArg0 {
-		artificialArgv0 = os.Args[0]
-	}
+	code := 0
+	for i, l := range m.Libraries {
+		g := l.generator()
+		g.Check = check
 
-	commandLineRaw := append([]string{artificialArgv0}, os.Args[1:]...)
-	var commandLineCooked []string
-	for _, arg := range commandLineRaw {
-		commandLineCooked = append(commandLineCooked, strconv.Quote(arg))
+		// go generate runs in the directory of the file, and a single directive generates all the files
+		g.CommandLineRaw = []string{argv0, "-config", relativeTo(filepath.Dir(g.Output), path)}
+		if selfGenerate {
+			g.CommandLineRaw = append(g.CommandLineRaw, "-generate")
+			g.SelfGenerate = i == 0
+		}
+		g.CommandLineCooked = quoteArgs(g.CommandLineRaw)
+
+		c := g.run()
+		if c == 1 {
+			// keep checking the rest
+			code = 1
+		} else if c != 0 {
+			return c
+		}
 	}
 
-	d := templateData{
-		SelfImportPath:       importPath,
-		SelfPackageName:      selfPackageName,
-		SelfExecutableName:   selfExecutableName,
-		SelfDocumentationURL: documentationURL,
-		CommandLineRaw:       commandLineRaw,
-		CommandLineCooked:    commandLineCooked,
-
-		SelfGenerate: selfGenerate,
+	return code
+}
 
-		DestinationPackageName: packageName,
-		TypeName:               outputType,
-		DllFileName:            filepath.Base(dllPath),
+// quoteArgs quotes the arguments for the generated go:generate directive.
+func quoteArgs(args []string) []string {
+	var ret []string
+	for _, arg := range args {
+		ret = append(ret, strconv.Quote(arg))
 	}
+	return ret
+}
 
-	// parse the PE header
-	peMeta, err := pe.New(dllPath, &pe.Options{})
-	if err != nil {
-		log.Printf("unable to read the DLL: %v\n", err)
-		os.Exit(66)
-	}
-	err = peMeta.Parse()
-	if err != nil {
-		log.Printf("unable to parse the DLL: %v\n", err)
-		os.Exit(65)
-	}
+// isCheckFlag tests if a command line argument is the -check flag.
+func isCheckFlag(arg string) bool {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	return strings.HasPrefix(arg, "-") && name == "check"
+}
 
-	for _, v := range peMeta.Export.Functions {
-		var fieldName string
-		if v.Name == "" {
-			fieldName = fmt.Sprintf("Ord%d", v.Ordinal)
-		} else {
-			fieldName = utils.FormatPublicType(strings.TrimLeft(v.Name, trimPrefix))
-		}
+// resolveLibraryPath resolves a bare DLL name the same way the OS loader does, checks that it is a file, and follows
+// linker scripts. On failure, the exit code is returned as well.
+func resolveLibraryPath(path string) (string, int, error) {
+	var err error
 
-		var typeName string
-		if lazy {
-			typeName = "*windows.LazyProc"
+	if utils.IsImplicitRelativePath(path) {
+		if runtime.GOOS == "windows" {
+			// mimic the behavior roughly where LoadLibrary() is called with LOAD_LIBRARY_SEARCH_SYSTEM32 flag set
+
+			system32, err := utils.GetSystemDirectory()
+			if err != nil {
+				return "", 72, fmt.Errorf("unable to get System32 directory: %w", err)
+			}
+
+			path = filepath.Join(system32, path)
 		} else {
-			typeName = "*windows.Proc"
+			// mimic the behavior of dlopen() with a bare soname
+			path, err = utils.FindSharedObject(path)
+			if err != nil {
+				return "", 66, fmt.Errorf("unable to find the shared object: %w", err)
+			}
 		}
-
-		d.Exports = append(d.Exports, export{
-			Field:    fieldName,
-			Type:     typeName,
-			Ordinal:  v.Ordinal,
-			Function: v.Name,
-		})
 	}
 
-	var b bytes.Buffer
-	err = srcTemplate.Execute(&b, d)
+	s, err := os.Stat(path)
 	if err != nil {
-		log.Printf("unable to fill the template: %v\n", err)
-		os.Exit(70)
+		return "", 66, fmt.Errorf("\"%s\" not found: %w", path, err)
+	}
+	if !s.Mode().IsRegular() {
+		return "", 66, fmt.Errorf("\"%s\" is not a file", path)
 	}
 
-	// format the output
-	src, err := format.Source(b.Bytes())
-	if err != nil {
-		log.Printf("unable to format the source code: %v\n", err)
-		os.Exit(70)
+	// like the loader does, follow linker scripts (e.g. libc.so) to the shared object they refer to
+	if target, ok := utils.LinkerScriptTarget(path); ok {
+		log.Printf("\"%s\" is a linker script, using \"%s\"", path, target)
+		path = target
 	}
 
-	// flush to the destination file
-	// perm is before umask as per doc (https://pkg.go.dev/os#WriteFile) so we are safe to use 0666 here
-	err = os.WriteFile(outputFileName, src, 0666)
-	if err != nil {
-		log.Printf("unable to write to file \"%s\": %v\n", outputFileName, err)
-		os.Exit(73)
+	return path, 0, nil
+}
+
+// libraryBaseName returns the name of a library without its extension or, for shared objects, without any version
+// suffix (e.g. "libz" for "libz.so.1").
+func libraryBaseName(path string) string {
+	name := filepath.Base(path)
+	if i := strings.Index(name, ".so"); i > 0 && (len(name) == i+3 || name[i+3] == '.') {
+		return name[:i]
 	}
 
-	return
+	return utils.BaseName(name)
 }
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jamesits/goinvoke/utils"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// manifest describes several libraries to generate bindings for in one run. It is read from YAML or JSON.
+type manifest struct {
+	Libraries []manifestLibrary `yaml:"libraries" json:"libraries"`
+}
+
+// manifestLibrary is a manifest entry. Relative paths are relative to the manifest file.
+type manifestLibrary struct {
+	DLL         scalarList        `yaml:"dll" json:"dll"`
+	Type        string            `yaml:"type,omitempty" json:"type,omitempty"`
+	Output      string            `yaml:"output,omitempty" json:"output,omitempty"`
+	TrimPrefix  scalarList        `yaml:"trim_prefix,omitempty" json:"trim_prefix,omitempty"`
+	TrimSuffix  scalarList        `yaml:"trim_suffix,omitempty" json:"trim_suffix,omitempty"`
+	Rewrite     []string          `yaml:"rewrite,omitempty" json:"rewrite,omitempty"`
+	Tags        []string          `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Lazy        bool              `yaml:"lazy,omitempty" json:"lazy,omitempty"`
+	Style       string            `yaml:"style,omitempty" json:"style,omitempty"`
+	Header      string            `yaml:"header,omitempty" json:"header,omitempty"`
+	Include     []string          `yaml:"include,omitempty" json:"include,omitempty"`
+	Exclude     []string          `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	Symbols     []string          `yaml:"symbols,omitempty" json:"symbols,omitempty"`
+	SymbolsFile string            `yaml:"symbols_file,omitempty" json:"symbols_file,omitempty"`
+	Rename      map[string]string `yaml:"rename,omitempty" json:"rename,omitempty"`
+	Def         string            `yaml:"def,omitempty" json:"def,omitempty"`
+	Template    string            `yaml:"template,omitempty" json:"template,omitempty"`
+
+	SkipForwarders  bool `yaml:"skip_forwarders,omitempty" json:"skip_forwarders,omitempty"`
+	CamelCase       bool `yaml:"camel_case,omitempty" json:"camel_case,omitempty"`
+	Initialisms     bool `yaml:"initialisms,omitempty" json:"initialisms,omitempty"`
+	KeepUnicode     bool `yaml:"keep_unicode,omitempty" json:"keep_unicode,omitempty"`
+	KeepUnderscores bool `yaml:"keep_underscores,omitempty" json:"keep_underscores,omitempty"`
+}
+
+// scalarList is either a single string or a list of strings, e.g. paths.
+type scalarList []string
+
+func (l *scalarList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = scalarList{value.Value}
+		return nil
+	}
+
+	return value.Decode((*[]string)(l))
+}
+
+func (l scalarList) MarshalYAML() (interface{}, error) {
+	if len(l) == 1 {
+		return l[0], nil
+	}
+
+	return []string(l), nil
+}
+
+func (l scalarList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+
+	return json.Marshal([]string(l))
+}
+
+// readManifest reads a manifest, and resolves the relative paths in it.
+func readManifest(path string) (*manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is a subset of YAML
+	m := &manifest{}
+	err = yaml.Unmarshal(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse \"%s\": %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for i := range m.Libraries {
+		l := &m.Libraries[i]
+		if len(l.DLL) == 0 {
+			return nil, fmt.Errorf("library #%d in \"%s\" has no dll", i+1, path)
+		}
+
+		for j, p := range l.DLL {
+			p = filepath.FromSlash(p)
+			l.DLL[j] = p
+
+			// bare names are looked up by the OS loader
+			if !utils.IsImplicitRelativePath(p) {
+				l.DLL[j] = resolveRelative(dir, p)
+			}
+		}
+		l.Output = resolveRelative(dir, l.Output)
+		if l.Output == "" {
+			// otherwise it would be written to the working directory
+			return nil, fmt.Errorf("library #%d in \"%s\" has no output", i+1, path)
+		}
+		l.Header = resolveRelative(dir, l.Header)
+		l.SymbolsFile = resolveRelative(dir, l.SymbolsFile)
+		l.Def = resolveRelative(dir, l.Def)
+		l.Template = resolveRelative(dir, l.Template)
+	}
+
+	return m, nil
+}
+
+// writeManifest writes a manifest to path, or to the stdout if path is "-". The format is JSON if the file name ends
+// with ".json", and YAML otherwise.
+func writeManifest(path string, m *manifest) error {
+	var b bytes.Buffer
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		e := json.NewEncoder(&b)
+		e.SetIndent("", "  ")
+		err = e.Encode(m)
+	} else {
+		e := yaml.NewEncoder(&b)
+		e.SetIndent(2)
+		err = e.Encode(m)
+		if err == nil {
+			err = e.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if path == "-" {
+		_, err = os.Stdout.Write(b.Bytes())
+		return err
+	}
+
+	return os.WriteFile(path, b.Bytes(), 0666)
+}
+
+// generator returns the generator of a manifest entry.
+func (l *manifestLibrary) generator() *generator {
+	return &generator{
+		DLLs:        l.DLL,
+		Type:        l.Type,
+		Output:      l.Output,
+		TrimPrefix:  l.TrimPrefix,
+		TrimSuffix:  l.TrimSuffix,
+		Rewrite:     l.Rewrite,
+		Tags:        l.Tags,
+		Lazy:        l.Lazy,
+		Style:       l.Style,
+		Header:      l.Header,
+		Include:     l.Include,
+		Exclude:     l.Exclude,
+		Symbols:     l.Symbols,
+		SymbolsFile: l.SymbolsFile,
+		Rename:      l.Rename,
+		Def:         l.Def,
+		Template:    l.Template,
+
+		SkipForwarders:  l.SkipForwarders,
+		CamelCase:       l.CamelCase,
+		Initialisms:     l.Initialisms,
+		KeepUnicode:     l.KeepUnicode,
+		KeepUnderscores: l.KeepUnderscores,
+	}
+}
+
+// manifest returns a manifest entry listing the chosen exports, as a starting point for a manifest at path. Relative
+// paths are rewritten to be relative to the manifest.
+func (g *generator) manifest(path string) (*manifestLibrary, int) {
+	libs, code := g.openLibraries()
+	if code != 0 {
+		return nil, code
+	}
+	g.outputDefaults(libs[0].fileName())
+
+	symbols, _, code := g.symbols(libs)
+	if code != 0 {
+		return nil, code
+	}
+
+	dir := "."
+	if path != "-" {
+		dir = filepath.Dir(path)
+	}
+
+	l := &manifestLibrary{
+		Type:       g.Type,
+		Output:     relativeTo(dir, g.Output),
+		TrimPrefix: g.TrimPrefix,
+		TrimSuffix: g.TrimSuffix,
+		Rewrite:    g.Rewrite,
+		Lazy:       g.Lazy,
+		Header:     relativeTo(dir, g.Header),
+		Rename:     g.Rename,
+		Def:        relativeTo(dir, g.Def),
+		Template:   relativeTo(dir, g.Template),
+
+		SkipForwarders:  g.SkipForwarders,
+		CamelCase:       g.CamelCase,
+		Initialisms:     g.Initialisms,
+		KeepUnicode:     g.KeepUnicode,
+		KeepUnderscores: g.KeepUnderscores,
+	}
+	if g.Style != styleStruct {
+		l.Style = g.Style
+	}
+	for _, p := range g.DLLs {
+		if !utils.IsImplicitRelativePath(p) {
+			p = relativeTo(dir, p)
+		}
+		l.DLL = append(l.DLL, p)
+	}
+	for _, tag := range g.Tags {
+		if tag != "" {
+			l.Tags = append(l.Tags, tag)
+		}
+	}
+	for _, s := range symbols {
+		l.Symbols = append(l.Symbols, filterName(s.symbol))
+	}
+
+	return l, 0
+}
+
+// resolveRelative returns path relative to dir, if it is relative.
+func resolveRelative(dir, path string) string {
+	path = filepath.FromSlash(path)
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+// relativeTo rewrites a path relative to the working directory to be relative to dir, if possible.
+func relativeTo(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return path
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return path
+	}
+
+	return filepath.ToSlash(rel)
+}
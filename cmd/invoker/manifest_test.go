@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "goinvoke.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+libraries:
+  - dll: libz.so.1
+    output: zlib/zlib_dll.go
+    symbols: [zlibVersion]
+    trim_prefix: crc32_
+    rename:
+      zlibVersion: Version
+  - dll: [foo.dll, lib/libfoo.so]
+    type: Foo
+    output: foo_dll.go
+    header: include/foo.h
+    lazy: true
+    tags: [foo]
+`), 0644))
+
+	m, err := readManifest(path)
+	if assert.NoError(t, err) && assert.Len(t, m.Libraries, 2) {
+		g := m.Libraries[0].generator()
+		assert.EqualValues(t, []string{"libz.so.1"}, g.DLLs)
+		assert.EqualValues(t, filepath.Join(dir, "zlib", "zlib_dll.go"), g.Output)
+		names, err := g.namer()
+		assert.NoError(t, err)
+		name, _ := names.fieldName(symbol{Name: "zlibVersion"})
+		assert.EqualValues(t, "Version", name)
+		name, _ = names.fieldName(symbol{Name: "crc32_combine"})
+		assert.EqualValues(t, "Combine", name)
+
+		g = m.Libraries[1].generator()
+		assert.EqualValues(t, []string{"foo.dll", filepath.Join(dir, "lib", "libfoo.so")}, g.DLLs)
+		assert.EqualValues(t, filepath.Join(dir, "include", "foo.h"), g.Header)
+		assert.True(t, g.Lazy)
+		assert.EqualValues(t, []string{"foo"}, g.Tags)
+	}
+
+	// the same manifest in JSON
+	path = filepath.Join(dir, "goinvoke.json")
+	assert.NoError(t, writeManifest(path, m))
+	m2, err := readManifest(path)
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, m.Libraries[1].DLL, m2.Libraries[1].DLL)
+		assert.EqualValues(t, m.Libraries[0].Rename, m2.Libraries[0].Rename)
+	}
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"libraries": [{"dll": "libz.so.1"}]}`), 0644))
+	_, err = readManifest(path)
+	assert.ErrorContains(t, err, "no output")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+)
+
+// mergedSymbol is a symbol exported by some of the merged libraries.
+type mergedSymbol struct {
+	symbol
+	GOOS []string // OSes whose library exports the symbol; nil if all of them do
+}
+
+// mergeLibraries merges the exports of libraries built for different OSes into one list, sorted by name. Ordinal-only
+// exports are never merged, and are sorted last.
+func mergeLibraries(libs []*library) (ret []mergedSymbol) {
+	allGOOS := map[string]bool{}
+	for _, lib := range libs {
+		allGOOS[lib.GOOS] = true
+	}
+
+	type key struct {
+		name    string
+		ordinal uint32
+		goos    string // ordinal-only exports are specific to one OS
+	}
+	type presence struct {
+		symbol symbol
+		goos   map[string]bool
+		libs   map[*library]bool
+	}
+	merged := map[key]*presence{}
+	var keys []key
+
+	for _, lib := range libs {
+		for _, s := range lib.Symbols {
+			k := key{name: s.Name}
+			if s.Name == "" {
+				k = key{ordinal: s.Ordinal, goos: lib.GOOS}
+			}
+
+			p, ok := merged[k]
+			if !ok {
+				p = &presence{symbol: s, goos: map[string]bool{}, libs: map[*library]bool{}}
+				merged[k] = p
+				keys = append(keys, k)
+			}
+			p.goos[lib.GOOS] = true
+			p.libs[lib] = true
+		}
+	}
+
+	for _, k := range keys {
+		p := merged[k]
+		m := mergedSymbol{symbol: p.symbol}
+
+		if len(p.goos) < len(allGOOS) {
+			for goos := range p.goos {
+				m.GOOS = append(m.GOOS, goos)
+			}
+			sort.Strings(m.GOOS)
+		} else if len(p.libs) < len(libs) {
+			// a struct field cannot tell apart libraries for the same OS
+			var missing []string
+			for _, lib := range libs {
+				if !p.libs[lib] {
+					missing = append(missing, lib.fileName())
+				}
+			}
+			log.Printf("warning: \"%s\" is missing from %s", p.symbol.Name, strings.Join(missing, ", "))
+		}
+
+		ret = append(ret, m)
+	}
+
+	sortSymbols(ret)
+	return
+}
+
+// missingGOOS returns the OSes of libs which are not in goos.
+func missingGOOS(libs []*library, goos []string) (ret []string) {
+	has := map[string]bool{}
+	for _, g := range goos {
+		has[g] = true
+	}
+	for _, lib := range libs {
+		if !has[lib.GOOS] {
+			has[lib.GOOS] = true
+			ret = append(ret, lib.GOOS)
+		}
+	}
+	sort.Strings(ret)
+	return
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMergeLibraries(t *testing.T) {
+	libs := []*library{
+		{Path: "foo.dll", Format: formatPE, GOOS: "windows", Symbols: []symbol{{Name: "foo_init", Ordinal: 1}, {Ordinal: 2}, {Name: "foo_win", Ordinal: 3}}},
+		{Path: "libfoo.so", Format: formatELF, GOOS: "linux", Symbols: []symbol{{Name: "foo_init"}, {Name: "foo_unix"}}},
+		{Path: "libfoo.dylib", Format: formatMachO, GOOS: "darwin", Symbols: []symbol{{Name: "foo_unix"}, {Name: "foo_init"}}},
+	}
+
+	merged := mergeLibraries(libs)
+	assert.EqualValues(t, []mergedSymbol{
+		{symbol: symbol{Name: "foo_init", Ordinal: 1}},
+		{symbol: symbol{Name: "foo_unix"}, GOOS: []string{"darwin", "linux"}},
+		{symbol: symbol{Name: "foo_win", Ordinal: 3}, GOOS: []string{"windows"}},
+		{symbol: symbol{Ordinal: 2}, GOOS: []string{"windows"}},
+	}, merged)
+
+	assert.EqualValues(t, []string{"windows"}, missingGOOS(libs, merged[1].GOOS))
+	assert.EqualValues(t, []string{"darwin", "linux"}, missingGOOS(libs, merged[3].GOOS))
+}
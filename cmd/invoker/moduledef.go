@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// moduleDefinition is a module-definition (.def) file, which describes the exports of a DLL to the linker.
+type moduleDefinition struct {
+	Library string // the DLL name, if specified
+	Exports []defExport
+}
+
+// defExport is an entry of the EXPORTS section, e.g. "name=internal @1 NONAME PRIVATE DATA".
+type defExport struct {
+	Name         string
+	InternalName string // or the forwarder, e.g. "other.func"
+	Ordinal      uint32 // 0 if not specified
+	NoName       bool   // exported by ordinal only
+	Private      bool   // left out of the import library
+	Data         bool
+}
+
+// defSectionKeywords start the sections of a module-definition file.
+var defSectionKeywords = map[string]bool{
+	"NAME": true, "LIBRARY": true, "DESCRIPTION": true, "STACKSIZE": true, "HEAPSIZE": true, "SECTIONS": true,
+	"SEGMENTS": true, "EXPORTS": true, "IMPORTS": true, "VERSION": true, "STUB": true, "CODE": true, "DATA": true,
+	"EXETYPE": true, "SUBSYSTEM": true,
+}
+
+// readModuleDefinition reads a module-definition file.
+func readModuleDefinition(path string) (*moduleDefinition, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseModuleDefinition(string(src))
+}
+
+// parseModuleDefinition parses the LIBRARY statement and the EXPORTS section of a module-definition file. Other
+// statements are ignored.
+func parseModuleDefinition(src string) (*moduleDefinition, error) {
+	m := &moduleDefinition{}
+	section := ""
+	var e *defExport
+	for i, line := range strings.Split(src, "\n") {
+		tokens, err := defTokens(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		for j := 0; j < len(tokens); j++ {
+			t := tokens[j]
+
+			// attributes of the current export
+			if section == "EXPORTS" && e != nil {
+				switch {
+				case t.text == "=" || t.text == "==":
+					if j+1 >= len(tokens) || tokens[j+1].text == "=" || tokens[j+1].text == "==" {
+						return nil, fmt.Errorf("line %d: missing the internal name of \"%s\"", i+1, e.Name)
+					}
+					j++
+					e.InternalName = tokens[j].text
+					continue
+				case strings.HasPrefix(t.text, "@") && !t.quoted:
+					number := strings.TrimPrefix(t.text, "@")
+					if number == "" && j+1 < len(tokens) {
+						// "@ 1"
+						j++
+						number = tokens[j].text
+					}
+					ordinal, err := strconv.ParseUint(number, 10, 16)
+					if err != nil || ordinal == 0 {
+						return nil, fmt.Errorf("line %d: invalid ordinal \"%s\" of \"%s\"", i+1, number, e.Name)
+					}
+					e.Ordinal = uint32(ordinal)
+					continue
+				case t.is("NONAME"):
+					e.NoName = true
+					continue
+				case t.is("PRIVATE"):
+					e.Private = true
+					continue
+				case t.is("DATA"), t.is("CONSTANT"):
+					e.Data = true
+					continue
+				}
+			}
+
+			if !t.quoted && defSectionKeywords[t.text] {
+				section = t.text
+				e = nil
+				continue
+			}
+
+			switch section {
+			case "NAME", "LIBRARY":
+				// e.g. "LIBRARY foo.dll BASE=0x10000000"
+				if m.Library == "" {
+					m.Library = t.text
+				}
+			case "EXPORTS":
+				m.Exports = append(m.Exports, defExport{Name: t.text})
+				e = &m.Exports[len(m.Exports)-1]
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// defToken is a word, a quoted string or an equal sign in a module-definition file.
+type defToken struct {
+	text   string
+	quoted bool
+}
+
+func (t defToken) is(keyword string) bool {
+	return !t.quoted && t.text == keyword
+}
+
+// defTokens splits a line into tokens, dropping the comment after ";".
+func defTokens(line string) (ret []defToken, err error) {
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == ';':
+			return ret, nil
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(line[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			ret = append(ret, defToken{text: line[i+1 : i+1+end], quoted: true})
+			i += end + 2
+		case c == '=':
+			if strings.HasPrefix(line[i:], "==") {
+				ret = append(ret, defToken{text: "=="})
+				i += 2
+			} else {
+				ret = append(ret, defToken{text: "="})
+				i++
+			}
+		default:
+			end := strings.IndexAny(line[i:], " \t\r,;=\"'")
+			if end < 0 {
+				end = len(line) - i
+			}
+			ret = append(ret, defToken{text: line[i : i+end]})
+			i += end
+		}
+	}
+
+	return ret, nil
+}
+
+// apply names the ordinal-only exports of lib and marks its data exports after the module definition. Conflicts
+// between both are returned as warnings; the module definition wins.
+func (m *moduleDefinition) apply(lib *library, defName string) (warnings []string) {
+	if m.Library != "" && !strings.EqualFold(libraryBaseName(m.Library), libraryBaseName(lib.fileName())) {
+		warnings = append(warnings, fmt.Sprintf("%s describes \"%s\", not \"%s\"", defName, m.Library, lib.fileName()))
+	}
+
+	byName := map[string]*symbol{}
+	byOrdinal := map[uint32]*symbol{}
+	for i := range lib.Symbols {
+		s := &lib.Symbols[i]
+		if s.Name != "" {
+			byName[s.Name] = s
+		}
+		byOrdinal[s.Ordinal] = s
+	}
+
+	for _, e := range m.Exports {
+		s := byName[e.Name]
+		switch {
+		case s != nil && e.Ordinal != 0 && s.Ordinal != 0 && s.Ordinal != e.Ordinal:
+			// import libraries do not know the ordinals of named exports
+			warnings = append(warnings, fmt.Sprintf("\"%s\" is @%d in %s, but @%d in %s", e.Name, s.Ordinal, lib.fileName(), e.Ordinal, defName))
+		case s != nil:
+			if e.NoName {
+				warnings = append(warnings, fmt.Sprintf("\"%s\" is NONAME in %s, but exported by name", e.Name, defName))
+			}
+		case e.Ordinal != 0 && byOrdinal[e.Ordinal] != nil:
+			s = byOrdinal[e.Ordinal]
+			if s.Name != "" {
+				warnings = append(warnings, fmt.Sprintf("@%d is \"%s\" in %s, but \"%s\" in %s", e.Ordinal, s.Name, lib.fileName(), e.Name, defName))
+				continue
+			}
+			if !e.NoName {
+				warnings = append(warnings, fmt.Sprintf("\"%s\" is exported by ordinal only, but not NONAME in %s", e.Name, defName))
+			}
+			s.Alias = e.Name
+		default:
+			warnings = append(warnings, fmt.Sprintf("\"%s\" is listed in %s, but not exported", e.Name, defName))
+			continue
+		}
+
+		if s.Forwarder == "" && s.Data != e.Data {
+			if e.Data {
+				warnings = append(warnings, fmt.Sprintf("\"%s\" is DATA in %s, but looks like code", e.Name, defName))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("\"%s\" is not DATA in %s, but looks like data", e.Name, defName))
+			}
+			s.Data = e.Data
+		}
+	}
+
+	return warnings
+}
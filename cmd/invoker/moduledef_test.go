@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseModuleDefinition(t *testing.T) {
+	m, err := parseModuleDefinition(`; comment
+LIBRARY "foo.dll" BASE=0x10000000
+EXPORTS
+	Init @1
+	hidden @2 NONAME ; by ordinal only
+	internal_name=Renamed @ 3 PRIVATE
+	Forwarded = bar.Baz
+	_Stdcall@8 @4
+	Counter DATA
+`)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &moduleDefinition{
+		Library: "foo.dll",
+		Exports: []defExport{
+			{Name: "Init", Ordinal: 1},
+			{Name: "hidden", Ordinal: 2, NoName: true},
+			{Name: "internal_name", InternalName: "Renamed", Ordinal: 3, Private: true},
+			{Name: "Forwarded", InternalName: "bar.Baz"},
+			{Name: "_Stdcall@8", Ordinal: 4},
+			{Name: "Counter", Data: true},
+		},
+	}, m)
+
+	_, err = parseModuleDefinition("EXPORTS\n\tfoo @bar\n")
+	assert.Error(t, err)
+	_, err = parseModuleDefinition("LIBRARY \"foo.dll\n")
+	assert.Error(t, err)
+}
+
+func TestModuleDefinitionApply(t *testing.T) {
+	lib := &library{Path: "foo.dll", Format: formatPE, GOOS: "windows", Symbols: []symbol{
+		{Name: "Init", Ordinal: 1},
+		{Ordinal: 2},
+		{Ordinal: 3},
+		{Name: "Counter", Ordinal: 4},
+		{Name: "Moved", Ordinal: 5},
+	}}
+	m := &moduleDefinition{
+		Library: "bar.dll",
+		Exports: []defExport{
+			{Name: "Init", Ordinal: 1},
+			{Name: "hidden", Ordinal: 2, NoName: true},
+			{Name: "Counter", Data: true},
+			{Name: "Moved", Ordinal: 6},
+			{Name: "Other", Ordinal: 1},
+			{Name: "Missing"},
+		},
+	}
+
+	assert.EqualValues(t, []string{
+		`foo.def describes "bar.dll", not "foo.dll"`,
+		`"Counter" is DATA in foo.def, but looks like code`,
+		`"Moved" is @5 in foo.dll, but @6 in foo.def`,
+		`@1 is "Init" in foo.dll, but "Other" in foo.def`,
+		`"Missing" is listed in foo.def, but not exported`,
+	}, m.apply(lib, "foo.def"))
+	assert.EqualValues(t, []symbol{
+		{Name: "Init", Ordinal: 1},
+		{Ordinal: 2, Alias: "hidden"},
+		{Ordinal: 3},
+		{Name: "Counter", Ordinal: 4, Data: true},
+		{Name: "Moved", Ordinal: 5},
+	}, lib.Symbols)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"github.com/jamesits/goinvoke/utils"
+	"regexp"
+	"strings"
+)
+
+// namer turns C names into Go identifiers. The same rules apply to the fields of the generated struct, and to the
+// types and constants from the header.
+type namer struct {
+	TrimPrefixes []string // the first matching one is trimmed
+	TrimSuffixes []string
+	Rewrites     []rewrite // applied in order, after trimming
+	Options      utils.IdentifierOptions
+	Overrides    map[string]string // C name to Go name, bypassing every other rule
+}
+
+// rewrite replaces the matches of a regular expression in a name.
+type rewrite struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// parseRewrite parses a "pattern=replacement" rule. The pattern ends at the last "=".
+func parseRewrite(rule string) (rewrite, error) {
+	i := strings.LastIndexByte(rule, '=')
+	if i < 0 {
+		return rewrite{}, fmt.Errorf("invalid rewrite rule \"%s\": no \"=\"", rule)
+	}
+
+	pattern, err := regexp.Compile(rule[:i])
+	if err != nil {
+		return rewrite{}, fmt.Errorf("invalid rewrite rule \"%s\": %w", rule, err)
+	}
+	return rewrite{Pattern: pattern, Replacement: rule[i+1:]}, nil
+}
+
+// name returns the Go identifier of a C name.
+func (n *namer) name(cName string) string {
+	if name, ok := n.Overrides[cName]; ok {
+		return name
+	}
+
+	name := cName
+	for _, prefix := range n.TrimPrefixes {
+		if len(name) > len(prefix) && strings.HasPrefix(name, prefix) {
+			name = name[len(prefix):]
+			break
+		}
+	}
+	for _, suffix := range n.TrimSuffixes {
+		if len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+			name = name[:len(name)-len(suffix)]
+			break
+		}
+	}
+	for _, r := range n.Rewrites {
+		name = r.Pattern.ReplaceAllString(name, r.Replacement)
+	}
+
+	// nothing might be left, e.g. of "_" or of a name in another script
+	if ret := n.identifier(name); ret != "" {
+		return ret
+	}
+	if ret := n.identifier(cName); ret != "" {
+		return ret
+	}
+	return "X"
+}
+
+// identifier formats a part of a name, without trimming or rewriting it.
+func (n *namer) identifier(name string) string {
+	return utils.FormatIdentifier(name, n.Options)
+}
+
+// fieldName returns the struct field name of an export. For C++ exports, the demangled signature is returned as well.
+func (n *namer) fieldName(s symbol) (name string, signature string) {
+	symbolName := s.Name
+	if symbolName == "" {
+		symbolName = s.Alias
+	}
+	if d, ok := demangle(symbolName); ok {
+		name, signature = d.FieldName(n.identifier), d.Signature
+	}
+	if name, ok := n.Overrides[filterName(s)]; ok {
+		return name, signature
+	}
+	if name != "" {
+		return name, signature
+	}
+	if symbolName == "" {
+		return fmt.Sprintf("Ord%d", s.Ordinal), signature
+	}
+	return n.name(symbolName), signature
+}
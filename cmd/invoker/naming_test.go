@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/jamesits/goinvoke/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNamer(t *testing.T) {
+	// a prefix, not a set of characters
+	n := &namer{TrimPrefixes: []string{"Rtl"}}
+	assert.EqualValues(t, "AllocateHeap", n.name("RtlAllocateHeap"))
+	assert.EqualValues(t, "LstrlenW", n.name("lstrlenW"))
+	assert.EqualValues(t, "Rtl", n.name("Rtl"))
+
+	n = &namer{
+		TrimPrefixes: []string{"z_", "zlib_"},
+		TrimSuffixes: []string{"_"},
+		Options:      utils.IdentifierOptions{CamelCase: true, Initialisms: true},
+		Overrides:    map[string]string{"zError": "ErrorString"},
+	}
+	r, err := parseRewrite(`^deflate(.*)$=compress$1`)
+	assert.NoError(t, err)
+	n.Rewrites = append(n.Rewrites, r)
+
+	assert.EqualValues(t, "CompressInit", n.name("deflateInit_"))
+	assert.EqualValues(t, "StreamID", n.name("z_stream_id"))
+	assert.EqualValues(t, "ErrorString", n.name("zError"))
+	assert.EqualValues(t, "X", n.name("_"))
+
+	name, signature := n.fieldName(symbol{Name: "_ZN3Bar6get_idEv"})
+	assert.EqualValues(t, "BarGetID", name)
+	assert.EqualValues(t, "Bar::get_id()", signature)
+	name, _ = n.fieldName(symbol{Ordinal: 3})
+	assert.EqualValues(t, "Ord3", name)
+	name, _ = n.fieldName(symbol{Ordinal: 3, Alias: "z_hidden"})
+	assert.EqualValues(t, "Hidden", name)
+
+	_, err = parseRewrite("no equal sign")
+	assert.Error(t, err)
+	_, err = parseRewrite("(=x")
+	assert.Error(t, err)
+}
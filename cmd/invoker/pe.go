@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"github.com/saferwall/pe"
+)
+
+// readPE reads the exports of a PE file.
+func readPE(path string) (*library, error) {
+	peMeta, err := pe.New(path, &pe.Options{})
+	if err != nil {
+		return nil, err
+	}
+	defer peMeta.Close()
+
+	err = peMeta.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the DLL: %w", err)
+	}
+
+	l := &library{
+		Path:   path,
+		Format: formatPE,
+		GOOS:   "windows",
+	}
+	for _, v := range peMeta.Export.Functions {
+		s := symbol{
+			Name:      v.Name,
+			Ordinal:   v.Ordinal,
+			Forwarder: v.Forwarder,
+		}
+		// the RVA of a forwarder points to its name
+		if v.Forwarder == "" {
+			s.Address = uint64(v.FunctionRVA)
+			s.Data = !isExecutableRVA(peMeta, v.FunctionRVA)
+		}
+		l.Symbols = append(l.Symbols, s)
+	}
+
+	return l, nil
+}
+
+// isExecutableRVA tests if rva points into a section containing code. Exports outside any section are assumed to be
+// code.
+func isExecutableRVA(f *pe.File, rva uint32) bool {
+	for _, section := range f.Sections {
+		start := section.Header.VirtualAddress
+		size := section.Header.VirtualSize
+		if size == 0 {
+			size = section.Header.SizeOfRawData
+		}
+		if rva >= start && rva-start < size {
+			return section.Header.Characteristics&pe.ImageSectionMemExecute != 0
+		}
+	}
+
+	return true
+}
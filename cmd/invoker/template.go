@@ -2,18 +2,62 @@ package main
 
 import (
 	_ "embed"
+	"github.com/jamesits/goinvoke/utils"
+	"strings"
 	"text/template"
+	"unicode"
 )
 
 //go:embed src.tmpl
 var srcTemplateString string
 var srcTemplate = template.Must(template.New("").Parse(srcTemplateString))
 
+// export kinds
+const (
+	kindFunction = "function"
+	kindData     = "data"
+)
+
+// export is an export of the DLL. The fields, as well as the ones of templateData and the types below, are available
+// to user-supplied templates, and are documented in the README.
 type export struct {
-	Field    string
-	Type     string
-	Ordinal  uint32
-	Function string
+	Field     string
+	Type      string
+	Ordinal   uint32 // PE only
+	RVA       uint32 // PE only; 0 if unknown (import libraries), for forwarders, or for several DLLs
+	Function  string // empty for ordinal-only exports
+	Forwarder string // PE only, e.g. "NTDLL.RtlAllocateHeap"
+	Kind      string // kindFunction or kindData
+	Demangled string // the demangled C++ signature; empty for C exports
+	GOOS      string // comma-separated; empty if the export is available everywhere
+	Comment   string
+	Doc       string // a line of documentation, e.g. the demangled C++ signature
+}
+
+// constant is a constant from a C header.
+type constant struct {
+	Name  string
+	Type  string
+	Value string
+	CName string
+}
+
+// typeDefinition is a type from a C header.
+type typeDefinition struct {
+	Name       string
+	Definition string
+	CName      string
+}
+
+// wrapper is a typed method calling an export, generated from a C prototype.
+type wrapper struct {
+	Method    string
+	Field     string
+	Function  string
+	Params    string
+	Args      []string
+	Result    string
+	Prototype string
 }
 
 type templateData struct {
@@ -25,10 +69,65 @@ type templateData struct {
 	CommandLineCooked    []string
 
 	SelfGenerate bool
+	LazyVars     bool   // package-level lazy procs instead of a struct
+	NewLazyDLL   string // the function creating the lazy DLL
+	ProcType     string
 
+	BuildConstraint        string
+	Imports                []string // the packages the built-in template needs
 	DllFileName            string
 	DestinationPackageName string
 	TypeName               string
 
 	Exports []export
+
+	HeaderFileName string
+	Constants      []constant
+	Types          []typeDefinition
+	Wrappers       []wrapper
+}
+
+// templateFuncs returns the helper functions available to user-supplied templates.
+func templateFuncs(names *namer) template.FuncMap {
+	return template.FuncMap{
+		// the naming rules of the generator, e.g. for a name from a C header
+		"goName": names.name,
+		// an exported identifier, without the naming rules
+		"public": utils.FormatPublicType,
+		// the unexported form of an identifier, e.g. for the implementation of an interface
+		"private": unexported,
+		"camelCase": func(name string) string {
+			return utils.FormatIdentifier(name, utils.IdentifierOptions{CamelCase: true, Initialisms: true})
+		},
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"join":       strings.Join,
+		"hasPrefix":  strings.HasPrefix,
+		"hasSuffix":  strings.HasSuffix,
+		"trimPrefix": strings.TrimPrefix,
+		"trimSuffix": strings.TrimSuffix,
+		"replace":    strings.ReplaceAll,
+	}
+}
+
+// parseTemplate parses a user-supplied template, in place of src.tmpl.
+func parseTemplate(name string, text string, names *namer) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs(names)).Parse(text)
+}
+
+// unexported lowers the leading upper case letters of an identifier, the way Go spells unexported names: "Foo" becomes
+// "foo", "ID" becomes "id", "IDs" becomes "ids", and "HTTPServer" becomes "httpServer".
+func unexported(name string) string {
+	runes := []rune(name)
+	i := 0
+	for i < len(runes) && unicode.IsUpper(runes[i]) {
+		i++
+	}
+	plural := i < len(runes) && runes[i] == 's' && (i+1 == len(runes) || !unicode.IsLower(runes[i+1]))
+	if i > 1 && i < len(runes) && unicode.IsLower(runes[i]) && !plural {
+		// the last one starts the next word
+		i--
+	}
+
+	return strings.ToLower(string(runes[:i])) + string(runes[i:])
 }
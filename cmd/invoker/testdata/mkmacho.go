@@ -0,0 +1,166 @@
+//go:build ignore
+
+// Generates minimal Mach-O dylibs for testing, since a macOS toolchain is not always available.
+// Usage: go run mkmacho.go
+//
+// Each dylib contains a __TEXT,__text section and a __DATA,__data section, with these symbols:
+//   - _foo_init, _foo_bar: exported functions
+//   - _foo_version: exported data
+//   - _foo_private: private extern function (not exported)
+//   - _foo_helper: local function (not exported)
+//   - _puts: undefined (imported)
+//
+// The arm64 slice of the fat dylib has an additional exported function _foo_neon.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+)
+
+const (
+	cpuAmd64 = 0x01000007
+	cpuArm64 = 0x0100000c
+
+	nExt  = 0x01
+	nPExt = 0x10
+	nUndf = 0x0
+	nSect = 0xe
+)
+
+type sym struct {
+	name  string
+	typ   uint8
+	sect  uint8
+	value uint64
+}
+
+func dylib(cpu, subcpu uint32, syms []sym) []byte {
+	le := binary.LittleEndian
+	const headerSize = 32
+	const segSize = 72 + 80 // segment_command_64 + 1 section_64
+	const idName = "@rpath/libfoo.dylib\x00\x00\x00\x00\x00"
+	idSize := 24 + len(idName)
+	const symtabSize = 24
+	const dysymtabSize = 80
+	sizeOfCmds := segSize*2 + idSize + symtabSize + dysymtabSize
+
+	textOff := uint32(headerSize + sizeOfCmds)
+	const textSize = 16
+	dataOff := textOff + textSize
+	const dataSize = 8
+	symOff := dataOff + dataSize
+
+	var strtab bytes.Buffer
+	strtab.WriteString(" \x00")
+	var nlist bytes.Buffer
+	for _, s := range syms {
+		_ = binary.Write(&nlist, le, uint32(strtab.Len()))
+		nlist.WriteByte(s.typ)
+		nlist.WriteByte(s.sect)
+		_ = binary.Write(&nlist, le, uint16(0))
+		_ = binary.Write(&nlist, le, s.value)
+		strtab.WriteString(s.name + "\x00")
+	}
+	for strtab.Len()%8 != 0 {
+		strtab.WriteByte(0)
+	}
+	strOff := symOff + uint32(nlist.Len())
+
+	var b bytes.Buffer
+	w := func(v ...any) {
+		for _, x := range v {
+			_ = binary.Write(&b, le, x)
+		}
+	}
+	name16 := func(s string) []byte {
+		ret := make([]byte, 16)
+		copy(ret, s)
+		return ret
+	}
+
+	// mach_header_64
+	w(uint32(0xfeedfacf), cpu, subcpu, uint32(6), uint32(5), uint32(sizeOfCmds), uint32(0x00100085), uint32(0))
+
+	// LC_SEGMENT_64 __TEXT
+	w(uint32(0x19), uint32(segSize), name16("__TEXT"), uint64(0), uint64(0x1000), uint64(0), uint64(textOff+textSize),
+		uint32(5), uint32(5), uint32(1), uint32(0))
+	w(name16("__text"), name16("__TEXT"), uint64(textOff), uint64(textSize), textOff, uint32(4), uint32(0), uint32(0),
+		uint32(0x80000400), uint32(0), uint32(0), uint32(0))
+
+	// LC_SEGMENT_64 __DATA
+	w(uint32(0x19), uint32(segSize), name16("__DATA"), uint64(0x1000), uint64(0x1000), uint64(dataOff), uint64(dataSize),
+		uint32(3), uint32(3), uint32(1), uint32(0))
+	w(name16("__data"), name16("__DATA"), uint64(0x1000), uint64(dataSize), dataOff, uint32(3), uint32(0), uint32(0),
+		uint32(0), uint32(0), uint32(0), uint32(0))
+
+	// LC_ID_DYLIB
+	w(uint32(0xd), uint32(idSize), uint32(24), uint32(2), uint32(0x10000), uint32(0x10000))
+	b.WriteString(idName)
+
+	// LC_SYMTAB
+	w(uint32(0x2), uint32(symtabSize), symOff, uint32(len(syms)), strOff, uint32(strtab.Len()))
+
+	// LC_DYSYMTAB; symbols are already sorted as local, external defined, undefined
+	var nLocal, nExtDef uint32
+	for _, s := range syms {
+		switch {
+		case s.typ&nExt == 0:
+			nLocal++
+		case s.typ&0xe != nUndf:
+			nExtDef++
+		}
+	}
+	w(uint32(0xb), uint32(dysymtabSize), uint32(0), nLocal, nLocal, nExtDef, nLocal+nExtDef, uint32(len(syms))-nLocal-nExtDef)
+	w(make([]uint32, 12))
+
+	b.Write(make([]byte, textSize))
+	b.Write(make([]byte, dataSize))
+	b.Write(nlist.Bytes())
+	b.Write(strtab.Bytes())
+	return b.Bytes()
+}
+
+func fat(slices [][3]any) []byte {
+	const align = 12
+	var b bytes.Buffer
+	be := binary.BigEndian
+	_ = binary.Write(&b, be, uint32(0xcafebabe))
+	_ = binary.Write(&b, be, uint32(len(slices)))
+
+	offset := uint32(1 << align)
+	for _, s := range slices {
+		data := s[2].([]byte)
+		_ = binary.Write(&b, be, []uint32{s[0].(uint32), s[1].(uint32), offset, uint32(len(data)), align})
+		offset += (uint32(len(data)) + 1<<align - 1) &^ (1<<align - 1)
+	}
+	for _, s := range slices {
+		b.Write(make([]byte, (1<<align-b.Len()%(1<<align))%(1<<align)))
+		b.Write(s[2].([]byte))
+	}
+
+	return b.Bytes()
+}
+
+func main() {
+	common := []sym{
+		{"_foo_helper", nSect, 1, 0x0},
+		{"_foo_private", nSect | nExt | nPExt, 1, 0x4},
+		{"_foo_bar", nSect | nExt, 1, 0x8},
+		{"_foo_init", nSect | nExt, 1, 0xc},
+		{"_foo_version", nSect | nExt, 2, 0x1000},
+	}
+	undefined := sym{"_puts", nUndf | nExt, 0, 0}
+
+	amd64 := dylib(cpuAmd64, 3, append(append([]sym{}, common...), undefined))
+	arm64 := dylib(cpuArm64, 0, append(append([]sym{}, common...), sym{"_foo_neon", nSect | nExt, 1, 0x0}, undefined))
+
+	if err := os.WriteFile("libfoo.dylib", amd64, 0644); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile("libfoo_fat.dylib", fat([][3]any{{uint32(cpuAmd64), uint32(3), amd64}, {uint32(cpuArm64), uint32(0), arm64}}), 0644); err != nil {
+		panic(err)
+	}
+}
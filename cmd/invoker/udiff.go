@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// edit is a line in an edit script.
+type edit struct {
+	op   byte // ' ', '-' or '+'
+	line string
+}
+
+// diffLines computes a shortest edit script from a to b with the linear space variant of Myers' algorithm.
+func diffLines(a, b []string) []edit {
+	return appendDiff(nil, a, b)
+}
+
+// appendDiff appends a shortest edit script from a to b to script. Common prefixes and suffixes are matched right away,
+// the rest is split at the middle snake, whose halves are diffed recursively.
+func appendDiff(script []edit, a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		script = append(script, edit{' ', a[prefix]})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			script = append(script, edit{'+', line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			script = append(script, edit{'-', line})
+		}
+	default:
+		x, y, ok := middleSnake(a, b)
+		if ok {
+			script = appendDiff(script, a[:x], b[:y])
+			script = appendDiff(script, a[x:], b[y:])
+		} else {
+			for _, line := range a {
+				script = append(script, edit{'-', line})
+			}
+			for _, line := range b {
+				script = append(script, edit{'+', line})
+			}
+		}
+	}
+
+	for _, line := range common {
+		script = append(script, edit{' ', line})
+	}
+	return script
+}
+
+// middleSnake searches the edit graph of a and b from both ends at once, and returns where the paths meet, which is
+// on a shortest edit script. Only the furthest reaching point of each diagonal is kept, so it needs O(len(a)+len(b))
+// memory. Diagonals running off the graph are not searched any further.
+func middleSnake(a, b []string) (x, y int, ok bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	delta := n - m
+	// if delta is odd, the paths meet while searching forward, otherwise while searching backward
+	odd := delta%2 != 0
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+
+	for d := 0; d <= maxD; d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			var x1 int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x1 = forward[offset+k+1]
+			} else {
+				x1 = forward[offset+k-1] + 1
+			}
+			y1 := x1 - k
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			forward[offset+k] = x1
+
+			switch {
+			case x1 > n:
+				forwardEnd += 2
+			case y1 > m:
+				forwardStart += 2
+			case odd:
+				i := offset + delta - k
+				if i >= 0 && i < len(backward) && backward[i] != -1 && x1 >= n-backward[i] {
+					return x1, y1, true
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			var x2 int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x2 = backward[offset+k+1]
+			} else {
+				x2 = backward[offset+k-1] + 1
+			}
+			y2 := x2 - k
+			for x2 < n && y2 < m && a[n-1-x2] == b[m-1-y2] {
+				x2++
+				y2++
+			}
+			backward[offset+k] = x2
+
+			switch {
+			case x2 > n:
+				backwardEnd += 2
+			case y2 > m:
+				backwardStart += 2
+			case !odd:
+				i := offset + delta - k
+				if i >= 0 && i < len(forward) && forward[i] != -1 && forward[i] >= n-x2 {
+					x1 := forward[i]
+					return x1, x1 - (delta - k), true
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// unifiedDiff returns the differences between a and b in the unified format with 3 lines of context, or an empty
+// string if they are the same.
+func unifiedDiff(aName, bName, a, b string) string {
+	const context = 3
+
+	script := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	for start := 0; start < len(script); {
+		// find the next change
+		for start < len(script) && script[start].op == ' ' {
+			start++
+		}
+		if start == len(script) {
+			break
+		}
+
+		// extend the hunk until there are more than 2*context unchanged lines
+		end := start
+		for i := start; i < len(script); i++ {
+			if script[i].op != ' ' {
+				end = i + 1
+			} else if i-end >= 2*context {
+				break
+			}
+		}
+
+		hunkStart := start - context
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		hunkEnd := end + context
+		if hunkEnd > len(script) {
+			hunkEnd = len(script)
+		}
+
+		// line numbers of the hunk in both files
+		aLine, bLine := 1, 1
+		for _, e := range script[:hunkStart] {
+			if e.op != '+' {
+				aLine++
+			}
+			if e.op != '-' {
+				bLine++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, e := range script[hunkStart:hunkEnd] {
+			if e.op != '+' {
+				aCount++
+			}
+			if e.op != '-' {
+				bCount++
+			}
+		}
+		if aCount == 0 {
+			aLine--
+		}
+		if bCount == 0 {
+			bLine--
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, e := range script[hunkStart:hunkEnd] {
+			out.WriteByte(e.op)
+			out.WriteString(e.line)
+			out.WriteByte('\n')
+		}
+
+		start = hunkEnd
+	}
+
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
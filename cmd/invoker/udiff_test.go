@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	assert.Empty(t, unifiedDiff("a", "b", "1\n2\n3\n", "1\n2\n3\n"))
+	assert.Empty(t, unifiedDiff("a", "b", "", ""))
+
+	a := strings.Join([]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"}, "\n") + "\n"
+	b := strings.Join([]string{"1", "2", "three", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13"}, "\n") + "\n"
+	assert.EqualValues(t, `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`, unifiedDiff("a", "b", a, b))
+
+	assert.EqualValues(t, "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+1\n+2\n", unifiedDiff("a", "b", "", "1\n2\n"))
+	assert.EqualValues(t, "--- a\n+++ b\n@@ -1,3 +1,3 @@\n-1\n+x\n 2\n-3\n+y\n", unifiedDiff("a", "b", "1\n2\n3\n", "x\n2\ny\n"))
+}
+
+func TestIsCheckFlag(t *testing.T) {
+	assert.True(t, isCheckFlag("-check"))
+	assert.True(t, isCheckFlag("--check=true"))
+	assert.False(t, isCheckFlag("check"))
+	assert.False(t, isCheckFlag("-checked"))
+}
@@ -3,6 +3,7 @@
 package goinvoke
 
 import (
+	"fmt"
 	"github.com/ebitengine/purego"
 	"github.com/jamesits/goinvoke/utils"
 	"golang.org/x/sys/unix"
@@ -13,14 +14,18 @@ import (
 	"unsafe"
 )
 
+// maxCallArgs is the number of arguments purego.SyscallN accepts.
+const maxCallArgs = 9
+
 var typeOfLazyProc = reflect.TypeOf((*LazyProc)(nil))
 var typeOfProc = reflect.TypeOf((*Proc)(nil))
 
 // convert a LazyDLL to DLL, assume it has been loaded.
 func unLazy(lazyDLL *LazyDLL) *DLL {
 	return &DLL{
-		Name:   lazyDLL.Name,
-		Handle: lazyDLL.Handle(),
+		Name:    lazyDLL.dll.Name,
+		Handle:  lazyDLL.Handle(),
+		release: lazyDLL.dll.release,
 	}
 }
 
@@ -32,10 +37,23 @@ func newLazyDLL(path string) *LazyDLL {
 	}
 }
 
+// resolveImagePath returns the path of the file that would be loaded for path.
+func resolveImagePath(path string) (string, error) {
+	if utils.IsImplicitRelativePath(path) {
+		return "", fmt.Errorf("\"%s\" is searched by the dynamic linker, a path to the file is required", path)
+	}
+	if target, ok := utils.LinkerScriptTarget(path); ok {
+		return target, nil
+	}
+	return path, nil
+}
+
 // A DLL implements access to a single DLL.
 type DLL struct {
 	Name   string
 	Handle uintptr
+
+	release func() // called after the DLL is unloaded, if not nil
 }
 
 // LoadDLL loads the named DLL file into memory.
@@ -46,10 +64,23 @@ type DLL struct {
 //
 // Use LazyDLL in golang.org/x/sys/windows for a secure way to
 // load system DLLs.
+//
+// If name is a GNU ld linker script, like libc.so on many distributions,
+// the shared object it refers to is loaded instead, and the returned
+// DLL is named after it.
 func LoadDLL(name string) (*DLL, error) {
 	h, err := purego.Dlopen(name, purego.RTLD_NOW|purego.RTLD_LOCAL)
 	if err != nil {
-		return nil, err
+		// libc.so and friends are often linker scripts, which only ld understands
+		target, ok := utils.LinkerScriptTarget(name)
+		if !ok {
+			return nil, err
+		}
+		h, err = purego.Dlopen(target, purego.RTLD_NOW|purego.RTLD_LOCAL)
+		if err != nil {
+			return nil, err
+		}
+		name = target
 	}
 	return &DLL{
 		Name:   name,
@@ -91,7 +122,11 @@ func (d *DLL) MustFindProc(name string) *Proc {
 
 // Release unloads DLL d from memory.
 func (d *DLL) Release() error {
-	return purego.Dlclose(d.Handle)
+	err := purego.Dlclose(d.Handle)
+	if d.release != nil {
+		d.release()
+	}
+	return err
 }
 
 // A Proc implements access to a procedure inside a DLL.
@@ -146,6 +181,8 @@ type LazyDLL struct {
 	dll    *DLL // non nil once DLL is loaded
 	Name   string
 	System bool // unused
+
+	release func() // passed to the loaded DLL
 }
 
 // Load loads DLL file d.Name into memory. It returns an error if fails.
@@ -173,6 +210,7 @@ func (d *LazyDLL) Load() error {
 			if e != nil {
 				return e
 			}
+			dll.release = d.release
 			// Non-racy version of:
 			// d.dll = dll
 			atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&d.dll)), unsafe.Pointer(dll))
@@ -5,9 +5,13 @@ package goinvoke
 import (
 	"github.com/jamesits/goinvoke/utils"
 	"golang.org/x/sys/windows"
+	"path/filepath"
 	"reflect"
 )
 
+// maxCallArgs is the number of arguments syscall.SyscallN accepts.
+const maxCallArgs = 42
+
 var typeOfLazyProc = reflect.TypeOf((*windows.LazyProc)(nil))
 var typeOfProc = reflect.TypeOf((*windows.Proc)(nil))
 
@@ -26,3 +30,15 @@ func newLazyDLL(path string) *windows.LazyDLL {
 		return windows.NewLazyDLL(path)
 	}
 }
+
+// resolveImagePath returns the path of the file that would be loaded for path.
+func resolveImagePath(path string) (string, error) {
+	if utils.IsImplicitRelativePath(path) {
+		system32, err := utils.GetSystemDirectory()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(system32, path), nil
+	}
+	return path, nil
+}
@@ -1,8 +1,47 @@
 package goinvoke
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrorNotFound        = errors.New("not found")
-	ErrorUnmarshalFailed = errors.New("unmarshal failed")
+	ErrorNotFound         = errors.New("not found")
+	ErrorUnmarshalFailed  = errors.New("unmarshal failed")
+	ErrorDigestMismatch   = errors.New("digest mismatch")
+	ErrorNotSigned        = errors.New("not signed by a trusted key")
+	ErrorUnsupportedType  = errors.New("unsupported type")
+	ErrorTooManyArguments = errors.New("too many arguments")
+	ErrorNotSharedLibrary = errors.New("not a shared library")
+
+	ErrArchitectureMismatch = errors.New("architecture mismatch")
 )
+
+// DigestMismatchError is returned when a DLL file does not match any of the expected digests.
+type DigestMismatchError struct {
+	Path   string
+	Actual [32]byte // SHA-256
+}
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("SHA-256 digest of \"%s\" is %x, which is not expected", e.Path, e.Actual)
+}
+
+func (e *DigestMismatchError) Unwrap() error {
+	return ErrorDigestMismatch
+}
+
+// ArchitectureMismatchError is returned when a DLL file is built for another OS or CPU than the current process.
+type ArchitectureMismatchError struct {
+	Path     string
+	Actual   string // e.g. "ELF ELFCLASS32 ELFDATA2LSB EM_386"; a comma-separated list for fat Mach-O files
+	Expected string // e.g. "ELF ELFCLASS64 ELFDATA2LSB EM_X86_64"
+}
+
+func (e *ArchitectureMismatchError) Error() string {
+	return fmt.Sprintf("\"%s\" is built for %s, but this process can only load %s", e.Path, e.Actual, e.Expected)
+}
+
+func (e *ArchitectureMismatchError) Unwrap() error {
+	return ErrArchitectureMismatch
+}
@@ -0,0 +1,89 @@
+//go:build linux
+
+package goinvoke
+
+import (
+	"fmt"
+	"github.com/ebitengine/purego"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+	"sync"
+)
+
+// rtldNoload makes dlopen(3) only return a handle to a library which is already loaded.
+const rtldNoload = 0x4
+
+// newImageFile copies image into an anonymous memfd, and returns a path that can be passed to dlopen(3).
+// The memfd is closed by release. It must be kept open as long as the DLL is loaded, otherwise the fd number (and so
+// the path) might be reused by another image, which the loader would mistake for the one it already has.
+func newImageFile(name string, image []byte) (path string, release func(), err error) {
+	fd, err := unix.MemfdCreate(name, unix.MFD_CLOEXEC)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// written through a dup, so the *os.File, and its finalizer, never own fd
+	f := os.NewFile(uintptr(fd), name)
+	fd, err = unix.FcntlInt(f.Fd(), unix.F_DUPFD_CLOEXEC, 0)
+	if err == nil {
+		_, err = f.Write(image)
+	}
+	_ = f.Close()
+	if err != nil {
+		if fd >= 0 {
+			_ = unix.Close(fd)
+		}
+		return "", nil, err
+	}
+
+	path, release = imageFile(fd)
+	return path, release, nil
+}
+
+// imageFile returns a path to fd that can be passed to dlopen(3), and release, which closes fd unless the image is
+// still loaded. fd is a raw fd, so it stays open even if nothing refers to the DLL any more: a DLL which is not
+// released explicitly is never unloaded either.
+func imageFile(fd int) (path string, release func()) {
+	path = fmt.Sprintf("/proc/self/fd/%d", fd)
+
+	var once sync.Once
+	return path, func() {
+		once.Do(func() {
+			// dlclose(3) does not unload a library which is still needed, e.g. by another library
+			if h, err := purego.Dlopen(path, purego.RTLD_NOW|rtldNoload); err == nil {
+				_ = purego.Dlclose(h)
+				return
+			}
+			_ = unix.Close(fd)
+		})
+	}
+}
+
+// openImageFile opens the file at path, checks its content with check, and returns a path to the same open file
+// description that can be passed to dlopen(3), so the file checked is exactly the file loaded.
+// Like newImageFile, the file is closed by release, and stays open as long as the DLL is loaded.
+func openImageFile(path string, check func(image []byte) error) (loadPath string, release func(), err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	image, err := io.ReadAll(f)
+	if err == nil {
+		err = check(image)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	// a dup shares the open file description, but is not owned by the *os.File and its finalizer
+	fd, err := unix.FcntlInt(f.Fd(), unix.F_DUPFD_CLOEXEC, 0)
+	if err != nil {
+		return "", nil, &os.PathError{Op: "dup", Path: path, Err: err}
+	}
+
+	loadPath, release = imageFile(fd)
+	return loadPath, release, nil
+}
@@ -0,0 +1,29 @@
+//go:build unix && !linux
+
+package goinvoke
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// openImageFile reads the file at path, checks its content with check, and returns a path to a private copy of that
+// exact content, so the file checked is exactly the file loaded. The copy is removed by release.
+func openImageFile(path string, check func(image []byte) error) (loadPath string, release func(), err error) {
+	image, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = check(image)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return newImageFile(filepath.Base(path), image)
+}
+
+// newPrivateDir creates a temporary directory only accessible by the current user.
+func newPrivateDir() (string, error) {
+	return os.MkdirTemp("", "goinvoke-")
+}
@@ -0,0 +1,80 @@
+//go:build !linux
+
+package goinvoke
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// staleImageDirs lists the temporary directories which could not be removed yet, because the image in them was still
+// loaded. Windows does not allow deleting a loaded DLL.
+var staleImageDirs struct {
+	sync.Mutex
+	dirs []string
+}
+
+// removeImageDir removes dir, or remembers it for retryRemoveImageDirs if that fails.
+func removeImageDir(dir string) {
+	if os.RemoveAll(dir) == nil {
+		return
+	}
+	staleImageDirs.Lock()
+	staleImageDirs.dirs = append(staleImageDirs.dirs, dir)
+	staleImageDirs.Unlock()
+}
+
+// retryRemoveImageDirs tries again to remove the directories removeImageDir failed to remove.
+func retryRemoveImageDirs() {
+	staleImageDirs.Lock()
+	defer staleImageDirs.Unlock()
+
+	dirs := staleImageDirs.dirs[:0]
+	for _, dir := range staleImageDirs.dirs {
+		if os.RemoveAll(dir) != nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	staleImageDirs.dirs = dirs
+}
+
+// newImageFile writes image into a private temporary directory, and returns the path to the file.
+// The directory is removed by release, or by a later call if the image is still loaded by then.
+func newImageFile(name string, image []byte) (path string, release func(), err error) {
+	retryRemoveImageDirs()
+
+	dir, err := newPrivateDir()
+	if err != nil {
+		return "", nil, err
+	}
+	var once sync.Once
+	release = func() { once.Do(func() { removeImageDir(dir) }) }
+
+	path = filepath.Join(dir, filepath.Base(name))
+	err = os.WriteFile(path, image, 0600)
+	if err != nil {
+		release()
+		return "", nil, err
+	}
+
+	// make sure nobody else could have swapped or altered the file before it is loaded; on Windows, the DACL of the
+	// directory takes care of that
+	if runtime.GOOS != "windows" {
+		for _, p := range []string{dir, path} {
+			s, err := os.Lstat(p)
+			if err != nil {
+				release()
+				return "", nil, err
+			}
+			if s.Mode()&os.ModeSymlink != 0 || s.Mode().Perm()&0077 != 0 {
+				release()
+				return "", nil, fmt.Errorf("temporary file \"%s\" is accessible by other users", p)
+			}
+		}
+	}
+
+	return path, release, nil
+}
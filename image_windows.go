@@ -0,0 +1,79 @@
+//go:build windows
+
+package goinvoke
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"golang.org/x/sys/windows"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+)
+
+// openImageFile opens the file at path, denying others to write to or delete it, then checks its content with check.
+// The file stays locked until release is called, so the file checked is exactly the file loaded.
+func openImageFile(path string, check func(image []byte) error) (loadPath string, release func(), err error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	h, err := windows.CreateFile(p, windows.GENERIC_READ, windows.FILE_SHARE_READ, nil, windows.OPEN_EXISTING, windows.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		return "", nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	f := os.NewFile(uintptr(h), path)
+
+	image, err := io.ReadAll(f)
+	if err == nil {
+		err = check(image)
+	}
+	if err != nil {
+		_ = f.Close()
+		return "", nil, err
+	}
+
+	var once sync.Once
+	return path, func() { once.Do(func() { _ = f.Close() }) }, nil
+}
+
+// newPrivateDir creates a temporary directory only accessible by the current user. Its DACL is protected, so nothing is
+// inherited from the parent directory, and grants full control to the current user only.
+func newPrivateDir() (string, error) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+	sd, err := windows.SecurityDescriptorFromString("D:P(A;OICI;FA;;;" + user.User.Sid.String() + ")")
+	if err != nil {
+		return "", err
+	}
+	sa := &windows.SecurityAttributes{SecurityDescriptor: sd}
+	sa.Length = uint32(unsafe.Sizeof(*sa))
+
+	for {
+		suffix := make([]byte, 8)
+		_, err = rand.Read(suffix)
+		if err != nil {
+			return "", err
+		}
+		dir := filepath.Join(os.TempDir(), "goinvoke-"+hex.EncodeToString(suffix))
+
+		p, err := windows.UTF16PtrFromString(dir)
+		if err != nil {
+			return "", err
+		}
+		err = windows.CreateDirectory(p, sa)
+		if errors.Is(err, windows.ERROR_ALREADY_EXISTS) {
+			continue
+		}
+		if err != nil {
+			return "", &os.PathError{Op: "mkdir", Path: dir, Err: err}
+		}
+		return dir, nil
+	}
+}
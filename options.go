@@ -0,0 +1,88 @@
+package goinvoke
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// Options controls how UnmarshalWithOptions loads a DLL. The zero value behaves exactly like Unmarshal.
+type Options struct {
+	// SHA256 lists the hex-encoded SHA-256 digests the DLL file is allowed to have. If not empty, a DLL file matching
+	// none of them is refused before it is loaded.
+	SHA256 []string
+
+	// TrustedKeys lists the ed25519 public keys the DLL file can be signed with. If not empty, a DLL file without a
+	// valid signature from any of them is refused before it is loaded.
+	TrustedKeys []ed25519.PublicKey
+
+	// Signature returns the detached signature of the DLL file at path. If nil, the signature is read from
+	// "<path>.sig", which contains either the raw 64-byte signature or its base64 encoding.
+	Signature func(path string) ([]byte, error)
+}
+
+// restricted returns true if the DLL content needs to be checked before it is loaded.
+func (o *Options) restricted() bool {
+	return o != nil && (len(o.SHA256) > 0 || len(o.TrustedKeys) > 0)
+}
+
+// check verifies a DLL image against all the restrictions in o.
+func (o *Options) check(path string, image []byte) error {
+	if len(o.SHA256) > 0 {
+		actual := sha256.Sum256(image)
+		matched := false
+		for _, expected := range o.SHA256 {
+			digest, err := hex.DecodeString(expected)
+			if err != nil || len(digest) != sha256.Size {
+				return fmt.Errorf("invalid SHA-256 digest \"%s\"", expected)
+			}
+			if [sha256.Size]byte(digest) == actual {
+				matched = true
+			}
+		}
+		if !matched {
+			return &DigestMismatchError{Path: path, Actual: actual}
+		}
+	}
+
+	if len(o.TrustedKeys) > 0 {
+		signature, err := o.signature(path)
+		if err != nil {
+			return fmt.Errorf("%w: \"%s\": %v", ErrorNotSigned, path, err)
+		}
+
+		matched := false
+		for _, key := range o.TrustedKeys {
+			if len(key) == ed25519.PublicKeySize && ed25519.Verify(key, image, signature) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%w: \"%s\"", ErrorNotSigned, path)
+		}
+	}
+
+	return nil
+}
+
+// signature returns the detached signature of the DLL file at path.
+func (o *Options) signature(path string) ([]byte, error) {
+	if o.Signature != nil {
+		return o.Signature(path)
+	}
+
+	signature, err := os.ReadFile(path + ".sig")
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) == ed25519.SignatureSize {
+		return signature, nil
+	}
+
+	return base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+}
@@ -0,0 +1,24 @@
+package goinvoke
+
+import (
+	"github.com/jamesits/goinvoke/utils"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// fieldAvailable tests if a struct field should be filled on the current OS. A field tagged with `goos:"..."`, a
+// comma-separated list of runtime.GOOS values, is left untouched on any other OS.
+func fieldAvailable(f reflect.StructField) bool {
+	goos := utils.GetStructTag(f, "goos")
+	if goos == "" {
+		return true
+	}
+
+	for _, s := range strings.Split(goos, ",") {
+		if strings.TrimSpace(s) == runtime.GOOS {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package goinvoke
+
+import (
+	"bytes"
+	"github.com/hashicorp/go-multierror"
+)
+
+// UnmarshalBytes is like Unmarshal, but loads the DLL from an in-memory image (e.g. one embedded with go:embed)
+// instead of a file on disk. name is only used to label the image and does not need to exist anywhere.
+//
+// On Linux the image is backed by an anonymous memfd and never touches the file system; the memfd stays open until
+// the DLL is released, even if nothing refers to it any more. On other OSes it is written
+// to a private temporary file first. The image is cleaned up when the DLL is released. Windows does not allow
+// deleting a loaded image, and DLLs are never freed there, so the temporary file is only cleaned up right away if
+// loading fails; otherwise later calls retry, and a file still loaded when the process exits is left behind.
+func UnmarshalBytes(name string, image []byte, v any) error {
+	err := checkArchitecture(name, bytes.NewReader(image))
+	if err != nil {
+		return multierror.Append(ErrorUnmarshalFailed, err)
+	}
+
+	path, release, err := newImageFile(name, image)
+	if err != nil {
+		return multierror.Append(ErrorUnmarshalFailed, err)
+	}
+
+	return unmarshalImage(path, release, v)
+}
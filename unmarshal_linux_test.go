@@ -3,9 +3,20 @@
 package goinvoke
 
 import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/hashicorp/go-multierror"
 	"github.com/jamesits/goinvoke/utils"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 type LibC struct {
@@ -29,3 +40,287 @@ func TestUnmarshal(t *testing.T) {
 	ret, _, _ = libC.StrCmp.Call(utils.StringToUintPtr("B"), utils.StringToUintPtr("A"))
 	assert.True(t, ret > 0)
 }
+
+// libraryPath returns the path of a library mapped into the current process.
+func libraryPath(t *testing.T, name string) string {
+	maps, err := os.ReadFile("/proc/self/maps")
+	assert.NoError(t, err)
+
+	for _, line := range strings.Split(string(maps), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 6 && filepath.Base(fields[5]) == name {
+			return fields[5]
+		}
+	}
+
+	t.Fatalf("%s is not loaded", name)
+	return ""
+}
+
+type LibM struct {
+	Sqrt *Proc `func:"sqrt"`
+}
+
+func TestUnmarshalBytes(t *testing.T) {
+	onDisk := LibM{}
+	err := Unmarshal("libm.so.6", &onDisk)
+	assert.NoError(t, err)
+
+	image, err := os.ReadFile(libraryPath(t, "libm.so.6"))
+	assert.NoError(t, err)
+
+	inMemory := LibM{}
+	err = UnmarshalBytes("libm.so.6", image, &inMemory)
+	assert.NoError(t, err)
+	assert.NotNil(t, inMemory.Sqrt)
+
+	// the image is loaded as a separate copy
+	assert.NotEqualValues(t, onDisk.Sqrt.Addr(), inMemory.Sqrt.Addr())
+	assert.NoError(t, inMemory.Sqrt.Dll.Release())
+}
+
+type LibZ struct {
+	ZlibVersion *Proc `func:"zlibVersion"`
+}
+
+// libZImage returns the image of libz, or skips the test if it is not installed.
+func libZImage(t *testing.T) (path string, image []byte) {
+	path, err := utils.FindSharedObject("libz.so.1")
+	if err != nil {
+		t.Skip("libz.so.1 is not installed")
+	}
+	image, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	return path, image
+}
+
+// collectGarbage runs the finalizers of everything which is no longer referenced.
+func collectGarbage() {
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// images dropped without being released stay loaded, so their fd numbers must not be reused for other images
+func TestUnmarshalBytesDropped(t *testing.T) {
+	_, libZ := libZImage(t)
+	err := Unmarshal("libm.so.6", &LibM{})
+	assert.NoError(t, err)
+	libM, err := os.ReadFile(libraryPath(t, "libm.so.6"))
+	assert.NoError(t, err)
+
+	err = UnmarshalBytes("libm.so.6", libM, &LibM{})
+	assert.NoError(t, err)
+	collectGarbage()
+
+	l := LibZ{}
+	err = UnmarshalBytes("libz.so.1", libZ, &l)
+	assert.NoError(t, err)
+	assert.NotNil(t, l.ZlibVersion)
+}
+
+func TestUnmarshalBytesInvalidImage(t *testing.T) {
+	l := LibC{}
+	err := UnmarshalBytes("libinvalid.so", []byte("not an ELF file"), &l)
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrorUnmarshalFailed)
+	assert.EqualValues(t, 2, len(err.(*multierror.Error).Errors))
+	assert.ErrorIs(t, err, ErrorNotSharedLibrary)
+	assert.Nil(t, l.Puts)
+}
+
+func TestUnmarshalWithOptionsSHA256(t *testing.T) {
+	err := Unmarshal("libm.so.6", &LibM{})
+	assert.NoError(t, err)
+	path := libraryPath(t, "libm.so.6")
+	image, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	digest := sha256.Sum256(image)
+
+	// any one of the digests matches
+	l := LibM{}
+	err = UnmarshalWithOptions(path, &l, &Options{SHA256: []string{strings.Repeat("0", 64), hex.EncodeToString(digest[:])}})
+	assert.NoError(t, err)
+	assert.NotNil(t, l.Sqrt)
+
+	// none of the digests matches
+	l = LibM{}
+	err = UnmarshalWithOptions(path, &l, &Options{SHA256: []string{strings.Repeat("0", 64)}})
+	assert.ErrorIs(t, err, ErrorUnmarshalFailed)
+	assert.ErrorIs(t, err, ErrorDigestMismatch)
+	var mismatch *DigestMismatchError
+	assert.ErrorAs(t, err, &mismatch)
+	assert.EqualValues(t, digest, mismatch.Actual)
+	assert.Nil(t, l.Sqrt)
+
+	// the file must be found without the help of the dynamic linker
+	err = UnmarshalWithOptions("libm.so.6", &l, &Options{SHA256: []string{hex.EncodeToString(digest[:])}})
+	assert.ErrorIs(t, err, ErrorUnmarshalFailed)
+	assert.Nil(t, l.Sqrt)
+}
+
+func TestUnmarshalWithOptionsSignature(t *testing.T) {
+	err := Unmarshal("libm.so.6", &LibM{})
+	assert.NoError(t, err)
+	image, err := os.ReadFile(libraryPath(t, "libm.so.6"))
+	assert.NoError(t, err)
+
+	trusted, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	untrusted, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "libm.so.6")
+	assert.NoError(t, os.WriteFile(path, image, 0600))
+
+	// unsigned
+	l := LibM{}
+	err = UnmarshalWithOptions(path, &l, &Options{TrustedKeys: []ed25519.PublicKey{trusted}})
+	assert.ErrorIs(t, err, ErrorNotSigned)
+	assert.Nil(t, l.Sqrt)
+
+	// signed, with a base64-encoded signature file
+	signature := ed25519.Sign(key, image)
+	assert.NoError(t, os.WriteFile(path+".sig", []byte(base64.StdEncoding.EncodeToString(signature)+"\n"), 0600))
+	err = UnmarshalWithOptions(path, &l, &Options{TrustedKeys: []ed25519.PublicKey{untrusted, trusted}})
+	assert.NoError(t, err)
+	assert.NotNil(t, l.Sqrt)
+
+	// signed by an untrusted key
+	l = LibM{}
+	err = UnmarshalWithOptions(path, &l, &Options{TrustedKeys: []ed25519.PublicKey{untrusted}})
+	assert.ErrorIs(t, err, ErrorNotSigned)
+	assert.Nil(t, l.Sqrt)
+
+	// signature from a callback
+	err = UnmarshalWithOptions(path, &l, &Options{
+		TrustedKeys: []ed25519.PublicKey{trusted},
+		Signature: func(p string) ([]byte, error) {
+			assert.EqualValues(t, path, p)
+			return signature, nil
+		},
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, l.Sqrt)
+}
+
+func TestUnmarshalWithOptionsDropped(t *testing.T) {
+	libZPath, libZ := libZImage(t)
+	libZDigest := sha256.Sum256(libZ)
+	err := Unmarshal("libm.so.6", &LibM{})
+	assert.NoError(t, err)
+	libMPath := libraryPath(t, "libm.so.6")
+	libM, err := os.ReadFile(libMPath)
+	assert.NoError(t, err)
+	libMDigest := sha256.Sum256(libM)
+
+	err = UnmarshalWithOptions(libMPath, &LibM{}, &Options{SHA256: []string{hex.EncodeToString(libMDigest[:])}})
+	assert.NoError(t, err)
+	collectGarbage()
+
+	l := LibZ{}
+	err = UnmarshalWithOptions(libZPath, &l, &Options{SHA256: []string{hex.EncodeToString(libZDigest[:])}})
+	assert.NoError(t, err)
+	assert.NotNil(t, l.ZlibVersion)
+}
+
+type LibMCrossPlatform struct {
+	Sqrt     FunctionPointer `func:"sqrt"`
+	Ord1     FunctionPointer `ordinal:"1" goos:"windows"`
+	Sqrtf128 FunctionPointer `func:"_sqrtf128_not_here" goos:"darwin,windows"`
+}
+
+func TestUnmarshalGOOSTag(t *testing.T) {
+	l := LibMCrossPlatform{}
+	err := Unmarshal("libm.so.6", &l)
+	assert.NoError(t, err)
+	assert.NotNil(t, l.Sqrt)
+	assert.Nil(t, l.Ord1)
+	assert.Nil(t, l.Sqrtf128)
+}
+
+func TestUnmarshalArchitectureMismatch(t *testing.T) {
+	err := Unmarshal("libm.so.6", &LibM{})
+	assert.NoError(t, err)
+	image, err := os.ReadFile(libraryPath(t, "libm.so.6"))
+	assert.NoError(t, err)
+
+	// e_machine
+	foreign := elf.EM_S390
+	if runtime.GOARCH == "s390x" {
+		foreign = elf.EM_X86_64
+	}
+	utils.HostByteOrder.PutUint16(image[18:], uint16(foreign))
+	path := filepath.Join(t.TempDir(), "libm.so.6")
+	assert.NoError(t, os.WriteFile(path, image, 0600))
+
+	l := LibM{}
+	err = Unmarshal(path, &l)
+	assert.ErrorIs(t, err, ErrorUnmarshalFailed)
+	assert.ErrorIs(t, err, ErrArchitectureMismatch)
+	var mismatch *ArchitectureMismatchError
+	assert.ErrorAs(t, err, &mismatch)
+	assert.Contains(t, mismatch.Actual, foreign.String())
+	assert.Equal(t, hostArchitecture(), mismatch.Expected)
+	assert.Nil(t, l.Sqrt)
+
+	// the image loaded is checked, too
+	err = UnmarshalBytes("libm.so.6", image, &l)
+	assert.ErrorIs(t, err, ErrArchitectureMismatch)
+	digest := sha256.Sum256(image)
+	err = UnmarshalWithOptions(path, &l, &Options{SHA256: []string{hex.EncodeToString(digest[:])}})
+	assert.ErrorIs(t, err, ErrArchitectureMismatch)
+	assert.Nil(t, l.Sqrt)
+}
+
+func TestUnmarshalNotSharedLibrary(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"libscript.so":  "/* GNU ld script */\nGROUP ( libnothing.a )\n",
+		"libarchive.so": "!<arch>\n",
+		"libempty.so":   "",
+	} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+		err := Unmarshal(path, &LibC{})
+		assert.ErrorIs(t, err, ErrorUnmarshalFailed, name)
+		assert.ErrorIs(t, err, ErrorNotSharedLibrary, name)
+	}
+}
+
+func TestUnmarshalLinkerScript(t *testing.T) {
+	err := Unmarshal("libc.so.6", &LibC{})
+	assert.NoError(t, err)
+	libc := libraryPath(t, "libc.so.6")
+
+	// a script in a private directory, so it does not depend on the development files being installed
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "libnothing.a"), []byte("!<arch>\n"), 0600))
+	script := filepath.Join(dir, "libc.so")
+	assert.NoError(t, os.WriteFile(script, []byte("/* GNU ld script */\nOUTPUT_FORMAT(elf64-x86-64)\n"+
+		"GROUP ( libnothing.a "+libc+" AS_NEEDED ( /lib64/ld-linux-x86-64.so.2 ) )\n"), 0600))
+
+	l := LibC{}
+	err = Unmarshal(script, &l)
+	assert.NoError(t, err)
+	if assert.NotNil(t, l.Puts) {
+		// the shared object chosen is reported
+		assert.Equal(t, libc, l.Puts.Dll.Name)
+	}
+
+	d, err := LoadDLL(script)
+	assert.NoError(t, err)
+	assert.Equal(t, libc, d.Name)
+	assert.NoError(t, d.Release())
+
+	// restrictions apply to the shared object, rather than to the script
+	image, err := os.ReadFile(libc)
+	assert.NoError(t, err)
+	digest := sha256.Sum256(image)
+	l = LibC{}
+	err = UnmarshalWithOptions(script, &l, &Options{SHA256: []string{hex.EncodeToString(digest[:])}})
+	assert.NoError(t, err)
+	assert.NotNil(t, l.Puts)
+}
@@ -0,0 +1,37 @@
+package goinvoke
+
+import (
+	"bytes"
+	"github.com/hashicorp/go-multierror"
+)
+
+// UnmarshalWithOptions is like Unmarshal, but puts restrictions defined in opts on the DLL file before loading it.
+// If a restriction is violated, nothing is loaded and the returned error wraps the reason (e.g. a
+// *DigestMismatchError).
+//
+// To avoid TOCTOU issues, the content is checked on the very file that is loaded later: on Linux the opened file is
+// loaded through /proc/self/fd; on Windows it is locked against modification until loaded; on other OSes a private
+// copy of the checked content is loaded.
+func UnmarshalWithOptions(path string, v any, opts *Options) error {
+	if !opts.restricted() {
+		return Unmarshal(path, v)
+	}
+
+	path, err := resolveImagePath(path)
+	if err != nil {
+		return multierror.Append(ErrorUnmarshalFailed, err)
+	}
+
+	loadPath, release, err := openImageFile(path, func(image []byte) error {
+		err := opts.check(path, image)
+		if err != nil {
+			return err
+		}
+		return checkArchitecture(path, bytes.NewReader(image))
+	})
+	if err != nil {
+		return multierror.Append(ErrorUnmarshalFailed, err)
+	}
+
+	return unmarshalImage(loadPath, release, v)
+}
@@ -3,19 +3,44 @@
 package goinvoke
 
 import (
-	"errors"
 	"github.com/hashicorp/go-multierror"
 	"github.com/jamesits/goinvoke/utils"
 	"reflect"
 )
 
 // Unmarshal loads the DLL into memory, then fills all struct fields with type *windows.LazyProc with exported functions.
+//
+// If path is not a bare name, the file header is checked first, so a DLL built for another architecture fails with a
+// *ArchitectureMismatchError, and a file which is not a DLL at all with an error wrapping ErrorNotSharedLibrary.
 func Unmarshal(path string, v any) error {
+	err := checkArchitectureFile(path)
+	if err != nil {
+		return multierror.Append(ErrorUnmarshalFailed, err)
+	}
+
+	return unmarshal(newLazyDLL(path), v)
+}
+
+// unmarshalImage is like Unmarshal, but release is called once the DLL is released, or right away if it could not
+// be loaded.
+func unmarshalImage(path string, release func(), v any) error {
+	ld := NewLazyDLL(path)
+	ld.release = release
+	err := unmarshal(ld, v)
+	if ld.dll == nil {
+		// nothing has been loaded, so nobody else is going to release it
+		release()
+	}
+
+	return err
+}
+
+// unmarshal loads ld, then fills v with its exports.
+func unmarshal(ld *LazyDLL, v any) error {
 	var err error
-	var syntheticErr = errors.New("unmarshal failed")
+	var syntheticErr = ErrorUnmarshalFailed
 	var errorOccurred = false
 
-	ld := newLazyDLL(path)
 	err = ld.Load()
 	if err != nil {
 		errorOccurred = true
@@ -39,7 +64,7 @@ func Unmarshal(path string, v any) error {
 
 		// get a reference of current attribute's value
 		valueField := valueReference.Field(i)
-		if !valueField.IsValid() || !valueField.CanSet() || procName == "" {
+		if !valueField.IsValid() || !valueField.CanSet() || procName == "" || !fieldAvailable(typeField) {
 			continue
 		}
 
@@ -11,12 +11,31 @@ import (
 )
 
 // Unmarshal loads the DLL into memory, then fills all struct fields with type *windows.LazyProc with exported functions.
+//
+// If path is not a bare name, the file header is checked first, so a DLL built for another architecture fails with a
+// *ArchitectureMismatchError, and a file which is not a DLL at all with an error wrapping ErrorNotSharedLibrary.
 func Unmarshal(path string, v any) error {
+	err := checkArchitectureFile(path)
+	if err != nil {
+		return multierror.Append(ErrorUnmarshalFailed, err)
+	}
+
+	return unmarshal(newLazyDLL(path), v)
+}
+
+// unmarshalImage is like Unmarshal, but release is called once the DLL is loaded (or failed to load). The DLL is never
+// freed, so release must cope with the image file still being in use.
+func unmarshalImage(path string, release func(), v any) error {
+	defer release()
+	return unmarshal(windows.NewLazyDLL(path), v)
+}
+
+// unmarshal loads ld, then fills v with its exports.
+func unmarshal(ld *windows.LazyDLL, v any) error {
 	var err error
 	var syntheticErr = ErrorUnmarshalFailed
 	var errorOccurred = false
 
-	ld := newLazyDLL(path)
 	err = ld.Load()
 	if err != nil {
 		errorOccurred = true
@@ -40,12 +59,16 @@ func Unmarshal(path string, v any) error {
 
 		// get a reference of current attribute's value
 		valueField := valueReference.Field(i)
-		if !valueField.IsValid() || !valueField.CanSet() || procName == "" {
+		if !valueField.IsValid() || !valueField.CanSet() || procName == "" || !fieldAvailable(typeField) {
 			continue
 		}
 
-		if utils.CompatibleType(valueField, typeOfLazyProc) {
-			// LazyProc only supports loading by name
+		// Windows specific: ordinal
+		ordinal, ordinalParsingError := strconv.ParseInt(utils.GetStructTag(typeField, "ordinal"), 10, 64)
+
+		// LazyProc only supports loading by name, so fields accepting both (e.g. FunctionPointer) get a Proc when
+		// an ordinal is given
+		if utils.CompatibleType(valueField, typeOfLazyProc) && (ordinalParsingError != nil || !utils.CompatibleType(valueField, typeOfProc)) {
 			proc := ld.NewProc(procName)
 			// try to load the proc now
 			err = proc.Find()
@@ -57,9 +80,6 @@ func Unmarshal(path string, v any) error {
 
 			utils.Set(valueField, proc)
 		} else if utils.CompatibleType(valueField, typeOfProc) {
-			// Windows specific: ordinal
-			ordinal, ordinalParsingError := strconv.ParseInt(utils.GetStructTag(typeField, "ordinal"), 10, 64)
-
 			var proc *windows.Proc
 			if ordinalParsingError == nil { // we have a valid ordinal
 				proc, err = d.FindProcByOrdinal(uintptr(ordinal))
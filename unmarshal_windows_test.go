@@ -262,3 +262,15 @@ func TestProcMissing(t *testing.T) {
 	assert.Nil(t, u.FunctionMissing1)
 	assert.Nil(t, u.FunctionMissing2)
 }
+
+func TestNewPrivateDir(t *testing.T) {
+	dir, err := newPrivateDir()
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sd, err := windows.GetNamedSecurityInfo(dir, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	assert.NoError(t, err)
+	control, _, err := sd.Control()
+	assert.NoError(t, err)
+	assert.NotZero(t, control&windows.SE_DACL_PROTECTED)
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
+var elfMachines = map[string]elf.Machine{
+	"386":      elf.EM_386,
+	"amd64":    elf.EM_X86_64,
+	"arm":      elf.EM_ARM,
+	"arm64":    elf.EM_AARCH64,
+	"loong64":  elf.EM_LOONGARCH,
+	"mips":     elf.EM_MIPS,
+	"mipsle":   elf.EM_MIPS,
+	"mips64":   elf.EM_MIPS,
+	"mips64le": elf.EM_MIPS,
+	"ppc64":    elf.EM_PPC64,
+	"ppc64le":  elf.EM_PPC64,
+	"riscv64":  elf.EM_RISCV,
+	"s390x":    elf.EM_S390,
+}
+
+var peMachines = map[string]uint16{
+	"386":   pe.IMAGE_FILE_MACHINE_I386,
+	"amd64": pe.IMAGE_FILE_MACHINE_AMD64,
+	"arm":   pe.IMAGE_FILE_MACHINE_ARMNT,
+	"arm64": pe.IMAGE_FILE_MACHINE_ARM64,
+}
+
+var peMachineNames = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_I386:  "i386",
+	pe.IMAGE_FILE_MACHINE_AMD64: "x86-64",
+	pe.IMAGE_FILE_MACHINE_ARM:   "ARM",
+	pe.IMAGE_FILE_MACHINE_ARMNT: "ARM Thumb-2",
+	pe.IMAGE_FILE_MACHINE_ARM64: "ARM64",
+	pe.IMAGE_FILE_MACHINE_IA64:  "Itanium",
+}
+
+var machOCPUs = map[string]macho.Cpu{
+	"386":   macho.Cpu386,
+	"amd64": macho.CpuAmd64,
+	"arm":   macho.CpuArm,
+	"arm64": macho.CpuArm64,
+}
+
+// HostELFMachine returns the class, the byte order and the machine of the ELF shared objects the current process can
+// load. ok is false if GOARCH has no known ELF machine.
+func HostELFMachine() (class elf.Class, data elf.Data, machine elf.Machine, ok bool) {
+	machine, ok = elfMachines[runtime.GOARCH]
+
+	class = elf.ELFCLASS32
+	if strconv.IntSize == 64 {
+		class = elf.ELFCLASS64
+	}
+	data = elf.ELFDATA2MSB
+	if HostByteOrder == binary.LittleEndian {
+		data = elf.ELFDATA2LSB
+	}
+	return
+}
+
+// HostPEMachine returns the Machine of the DLLs the current process can load. ok is false if GOARCH has no known PE
+// machine.
+func HostPEMachine() (machine uint16, ok bool) {
+	machine, ok = peMachines[runtime.GOARCH]
+	return
+}
+
+// HostMachOCPU returns the CPU type of the Mach-O images the current process can load. ok is false if GOARCH has no
+// known Mach-O CPU type.
+func HostMachOCPU() (cpu macho.Cpu, ok bool) {
+	cpu, ok = machOCPUs[runtime.GOARCH]
+	return
+}
+
+// PEMachineName describes the Machine of a PE file, e.g. "x86-64 (0x8664)".
+func PEMachineName(machine uint16) string {
+	if name, ok := peMachineNames[machine]; ok {
+		return fmt.Sprintf("%s (%#x)", name, machine)
+	}
+	return fmt.Sprintf("%#x", machine)
+}
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"bytes"
+	"debug/elf"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// linkerScriptMaxSize is the size of the largest file read as a linker script. Real ones are a few hundred bytes.
+const linkerScriptMaxSize = 64 * 1024
+
+// linkerScriptMaxDepth limits how many linker scripts referring to each other are followed.
+const linkerScriptMaxDepth = 4
+
+// ParseLinkerScript returns the input files of a GNU ld linker script, as listed by its GROUP, INPUT and AS_NEEDED
+// commands, in order. "-lname" inputs are returned as "libname.so". ok is false if s is not a linker script, or if it
+// has no input.
+func ParseLinkerScript(s string) (inputs []string, ok bool) {
+	// comments are C style only
+	for {
+		start := strings.Index(s, "/*")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start+2:], "*/")
+		if end < 0 {
+			return nil, false
+		}
+		s = s[:start] + " " + s[start+2+end+2:]
+	}
+
+	var tokens []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' || c == ';':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, s[i:i+1])
+			i++
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, false
+			}
+			tokens = append(tokens, s[i:i+1+end+1])
+			i += end + 2
+		case c < 0x20 || c >= 0x7f:
+			// binary files are not scripts
+			return nil, false
+		default:
+			end := strings.IndexAny(s[i:], " \t\n\r,;()\"")
+			if end < 0 {
+				end = len(s) - i
+			}
+			tokens = append(tokens, s[i:i+end])
+			i += end
+		}
+	}
+
+	// commands nest, e.g. GROUP ( libc.so.6 AS_NEEDED ( ld.so ) )
+	var commands []string
+	for i, t := range tokens {
+		switch {
+		case t == "(":
+			if i == 0 || tokens[i-1] == "(" || tokens[i-1] == ")" {
+				return nil, false
+			}
+		case t == ")":
+			if len(commands) == 0 {
+				return nil, false
+			}
+			commands = commands[:len(commands)-1]
+		case i+1 < len(tokens) && tokens[i+1] == "(":
+			commands = append(commands, t)
+		case len(commands) > 0:
+			switch commands[len(commands)-1] {
+			case "GROUP", "INPUT", "AS_NEEDED":
+				t = strings.Trim(t, "\"")
+				if name, ok := strings.CutPrefix(t, "-l"); ok {
+					t = "lib" + name + ".so"
+				}
+				inputs = append(inputs, t)
+			}
+		}
+	}
+	if len(commands) > 0 {
+		return nil, false
+	}
+
+	return inputs, len(inputs) > 0
+}
+
+// LinkerScriptTarget returns the ELF shared object a GNU ld linker script refers to, e.g. the real libc.so.6 for
+// libc.so on many distributions, which dlopen(3) refuses to load. Bare names are searched for like ld.so(8) does, and
+// relative inputs in the directory of the script first. Inputs are tried in order: static archives are skipped, and
+// scripts referring to other scripts are followed. ok is false if path is not a linker script, or if it does not refer
+// to any shared object.
+func LinkerScriptTarget(path string) (target string, ok bool) {
+	return linkerScriptTarget(path, 0)
+}
+
+func linkerScriptTarget(path string, depth int) (string, bool) {
+	p, err := FindSharedObject(path)
+	if err != nil {
+		return "", false
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return "", false
+	}
+	data, err := io.ReadAll(io.LimitReader(f, linkerScriptMaxSize+1))
+	_ = f.Close()
+	if err != nil || len(data) > linkerScriptMaxSize || bytes.HasPrefix(data, []byte("\x7fELF")) {
+		return "", false
+	}
+
+	inputs, ok := ParseLinkerScript(string(data))
+	if !ok {
+		return "", false
+	}
+	for _, input := range inputs {
+		var candidates []string
+		if filepath.IsAbs(input) {
+			candidates = append(candidates, input)
+		} else {
+			candidates = append(candidates, filepath.Join(filepath.Dir(p), input))
+			if found, err := FindSharedObject(input); err == nil {
+				candidates = append(candidates, found)
+			}
+		}
+
+		for _, c := range candidates {
+			if isSharedObject(c) {
+				return c, true
+			}
+			if depth < linkerScriptMaxDepth {
+				if target, ok := linkerScriptTarget(c, depth+1); ok {
+					return target, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// isSharedObject tests if the file at path is an ELF shared object.
+func isSharedObject(path string) bool {
+	f, err := elf.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	return f.Type == elf.ET_DYN
+}
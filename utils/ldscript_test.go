@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLinkerScript(t *testing.T) {
+	inputs, ok := ParseLinkerScript(`/* GNU ld script
+   Use the shared library, but some functions are only in
+   the static library, so try that secondarily.  */
+OUTPUT_FORMAT(elf64-x86-64)
+GROUP ( /lib/x86_64-linux-gnu/libc.so.6 /usr/lib/x86_64-linux-gnu/libc_nonshared.a  AS_NEEDED ( /lib64/ld-linux-x86-64.so.2 ) )
+`)
+	assert.True(t, ok)
+	assert.EqualValues(t, []string{"/lib/x86_64-linux-gnu/libc.so.6", "/usr/lib/x86_64-linux-gnu/libc_nonshared.a", "/lib64/ld-linux-x86-64.so.2"}, inputs)
+
+	inputs, ok = ParseLinkerScript("INPUT(libncurses.so.6 -ltinfo)\n")
+	assert.True(t, ok)
+	assert.EqualValues(t, []string{"libncurses.so.6", "libtinfo.so"}, inputs)
+
+	inputs, ok = ParseLinkerScript(`INPUT("lib with spaces.so", libfoo.so.1)`)
+	assert.True(t, ok)
+	assert.EqualValues(t, []string{"lib with spaces.so", "libfoo.so.1"}, inputs)
+
+	for _, s := range []string{
+		"",
+		"OUTPUT_FORMAT(elf64-x86-64)",    // no input
+		"GROUP ( libc.so.6 ",             // unbalanced
+		"GROUP ( libc.so.6 ) )",          // unbalanced
+		"/* GROUP ( libc.so.6 )",         // unterminated comment
+		"\x7fELF\x02\x01\x01GROUP(a.so)", // binary
+		"just some text",
+	} {
+		_, ok = ParseLinkerScript(s)
+		assert.False(t, ok, s)
+	}
+}
+
+// writeSharedObject writes the header of an empty ELF shared object.
+func writeSharedObject(t *testing.T, path string) {
+	header := make([]byte, 64)
+	copy(header, "\x7fELF\x02\x01\x01")
+	binary.LittleEndian.PutUint16(header[16:], 3)  // e_type: ET_DYN
+	binary.LittleEndian.PutUint16(header[18:], 62) // e_machine: EM_X86_64
+	binary.LittleEndian.PutUint32(header[20:], 1)  // e_version
+	binary.LittleEndian.PutUint16(header[52:], 64) // e_ehsize
+	assert.NoError(t, os.WriteFile(path, header, 0644))
+}
+
+func TestLinkerScriptTarget(t *testing.T) {
+	dir := t.TempDir()
+	writeSharedObject(t, filepath.Join(dir, "libfoo.so.1"))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "libfoo.a"), []byte("!<arch>\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "libfoo.so"), []byte("GROUP ( libfoo.a libmissing.so.1 libfoo.so.1 )\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "libbar.so"), []byte("INPUT ( "+filepath.Join(dir, "libfoo.so")+" )\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "libstatic.so"), []byte("GROUP ( libfoo.a )\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "libloop.so"), []byte("INPUT ( libloop.so )\n"), 0644))
+
+	// static archives and missing files are skipped
+	target, ok := LinkerScriptTarget(filepath.Join(dir, "libfoo.so"))
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "libfoo.so.1"), target)
+
+	// scripts referring to scripts are followed
+	target, ok = LinkerScriptTarget(filepath.Join(dir, "libbar.so"))
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "libfoo.so.1"), target)
+
+	for _, name := range []string{"libstatic.so", "libloop.so", "libfoo.so.1", "libfoo.a", "libnothing.so"} {
+		_, ok = LinkerScriptTarget(filepath.Join(dir, name))
+		assert.False(t, ok, name)
+	}
+}
@@ -2,10 +2,15 @@ package utils
 
 import (
 	"bufio"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
+// SharedObjectDefaultPaths are searched by ld.so after everything else.
+var SharedObjectDefaultPaths = []string{"/lib64", "/usr/lib64", "/lib", "/usr/lib"}
+
 func PathsFromEnvironmentVariable(env string) []string {
 	v := os.Getenv(env)
 	if v == "" {
@@ -15,6 +20,7 @@ func PathsFromEnvironmentVariable(env string) []string {
 	return strings.Split(v, ":")
 }
 
+// PathsFromFileLines reads a list of paths from an ld.so.conf(5) style file, following "include" directives.
 func PathsFromFileLines(file string) (ret []string) {
 	f, err := os.Open(file)
 	if err != nil {
@@ -25,9 +31,21 @@ func PathsFromFileLines(file string) (ret []string) {
 	scanner := bufio.NewScanner(f)
 	scanner.Split(bufio.ScanLines)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "include ") {
-			ret = append(ret, PathsFromFileLines(strings.TrimLeft(line, "include "))...)
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if pattern, ok := strings.CutPrefix(line, "include "); ok {
+			pattern = strings.TrimSpace(pattern)
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(filepath.Dir(file), pattern)
+			}
+			includes, _ := filepath.Glob(pattern)
+			for _, include := range includes {
+				ret = append(ret, PathsFromFileLines(include)...)
+			}
 		} else {
 			ret = append(ret, line)
 		}
@@ -35,3 +53,33 @@ func PathsFromFileLines(file string) (ret []string) {
 
 	return
 }
+
+// SharedObjectSearchPaths returns the directories ld.so(8) searches for a shared object, in order.
+func SharedObjectSearchPaths() (ret []string) {
+	ret = append(ret, PathsFromEnvironmentVariable("LD_LIBRARY_PATH")...)
+	ret = append(ret, PathsFromFileLines("/etc/ld.so.conf")...)
+	ret = append(ret, SharedObjectDefaultPaths...)
+	return
+}
+
+// FindSharedObject resolves a shared object name (e.g. "libz.so.1") to a path the same way ld.so(8) does.
+// Names containing a slash are returned as is.
+func FindSharedObject(name string) (string, error) {
+	if strings.ContainsRune(name, '/') {
+		return name, nil
+	}
+
+	for _, dir := range SharedObjectSearchPaths() {
+		if dir == "" {
+			continue
+		}
+
+		p := filepath.Join(dir, name)
+		s, err := os.Stat(p)
+		if err == nil && s.Mode().IsRegular() {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("\"%s\" not found in the ld.so search path", name)
+}
@@ -20,3 +20,85 @@ func FormatPublicType(name string) string {
 
 	return name
 }
+
+// IdentifierOptions changes how FormatIdentifier treats what FormatPublicType would drop or leave alone.
+type IdentifierOptions struct {
+	CamelCase       bool // start a new word after each dropped character, e.g. "foo_bar" becomes "FooBar"
+	Initialisms     bool // spell common initialisms in upper case, e.g. "UserId" becomes "UserID"
+	KeepUnicode     bool // keep non-ASCII letters and digits
+	KeepUnderscores bool // turn leading underscores into "X" rather than dropping them, so "_foo" and "foo" differ
+}
+
+// initialisms are spelled in upper case in Go identifiers, see https://go.dev/wiki/CodeReviewComments#initialisms.
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true, "GUID": true,
+	"HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true, "QPS": true,
+	"RAM": true, "RHS": true, "RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true, "URL": true,
+	"UTF8": true, "VM": true, "XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// FormatIdentifier is like FormatPublicType, with options. An empty string is returned if nothing is left of name.
+func FormatIdentifier(name string, o IdentifierOptions) string {
+	var b strings.Builder
+
+	trimmed := strings.TrimLeft(name, "_")
+	if o.KeepUnderscores {
+		b.WriteString(strings.Repeat("X", len(name)-len(trimmed)))
+	}
+
+	upper := true
+	for _, r := range trimmed {
+		ascii := r < unicode.MaxASCII && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		if !ascii && !(o.KeepUnicode && r > unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))) {
+			upper = upper || o.CamelCase
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+
+	ret := b.String()
+	if ret == "" {
+		return ""
+	}
+	first := []rune(ret)[0]
+	if unicode.IsDigit(first) {
+		ret = "T" + ret
+	} else if !unicode.IsUpper(first) {
+		// e.g. CJK characters, which have no case
+		ret = "X" + ret
+	}
+
+	if o.Initialisms {
+		ret = fixInitialisms(ret)
+	}
+	return ret
+}
+
+// fixInitialisms spells the words of a CamelCase identifier which are initialisms in upper case.
+func fixInitialisms(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		// a word ends before an upper case letter following something else
+		if i < len(runes) && !(unicode.IsUpper(runes[i]) && !unicode.IsUpper(runes[i-1])) {
+			continue
+		}
+
+		word := string(runes[start:i])
+		if upperWord := strings.ToUpper(word); upperWord != word &&
+			(initialisms[upperWord] || initialisms[strings.TrimRight(upperWord, "0123456789")]) {
+			word = upperWord
+		}
+		b.WriteString(word)
+		start = i
+	}
+
+	return b.String()
+}
@@ -26,3 +26,24 @@ func TestFormatPublicType(t *testing.T) {
 		assert.EqualValues(t, resultSet[i], FormatPublicType(testSet[i]))
 	}
 }
+
+func TestFormatIdentifier(t *testing.T) {
+	assert.EqualValues(t, "Deflateinit", FormatIdentifier("deflate_init", IdentifierOptions{}))
+	assert.EqualValues(t, "DeflateInit", FormatIdentifier("deflate_init", IdentifierOptions{CamelCase: true}))
+	assert.EqualValues(t, "T1test", FormatIdentifier("1test", IdentifierOptions{}))
+	assert.EqualValues(t, "", FormatIdentifier("__", IdentifierOptions{}))
+
+	assert.EqualValues(t, "Foo", FormatIdentifier("_foo", IdentifierOptions{}))
+	assert.EqualValues(t, "XXFoo", FormatIdentifier("__foo", IdentifierOptions{KeepUnderscores: true}))
+
+	assert.EqualValues(t, "Tst", FormatIdentifier("té测st", IdentifierOptions{}))
+	assert.EqualValues(t, "Té测st", FormatIdentifier("té测st", IdentifierOptions{KeepUnicode: true}))
+	assert.EqualValues(t, "X测试", FormatIdentifier("测试", IdentifierOptions{KeepUnicode: true}))
+
+	o := IdentifierOptions{CamelCase: true, Initialisms: true}
+	assert.EqualValues(t, "GetUserID", FormatIdentifier("get_user_id", o))
+	assert.EqualValues(t, "URLToUTF8", FormatIdentifier("url_to_utf8", o))
+	assert.EqualValues(t, "Identity", FormatIdentifier("identity", o))
+	assert.EqualValues(t, "HTTPServer", FormatIdentifier("HTTPServer", o))
+	assert.EqualValues(t, "IP4Addr", FormatIdentifier("ip4_addr", o))
+}